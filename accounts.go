@@ -55,28 +55,18 @@ type AccountHistory struct {
 }
 
 // An AccountCollection is an iterator of Accounts.
-type AccountCollection struct {
-	pageableCollection
-}
+type AccountCollection = Iterator[*Account]
 
 // An AccountHistoryCollection is an iterator of AccountHistorys.
-type AccountHistoryCollection struct {
-	pageableCollection
-	id *uuid.UUID
-}
+type AccountHistoryCollection = Iterator[*AccountHistory]
 
-// An AccountHoldCollection is an iterator of AccountHoldCollections.
-type AccountHoldCollection struct {
-	pageableCollection
-	id *uuid.UUID
-}
+// An AccountHoldCollection is an iterator of AccountHolds.
+type AccountHoldCollection = Iterator[*AccountHold]
 
 // GetAccounts gets all associated Accounts.
 func (accessInfo *AccessInfo) GetAccounts() *AccountCollection {
-	accountCollection := AccountCollection{
-		pageableCollection: accessInfo.newPageableCollection(false),
-	}
-	return &accountCollection
+	// GET /accounts
+	return newIterator[*Account](accessInfo, http.MethodGet, "/accounts", "", "", false)
 }
 
 // GetAccount gets an Account with a specified accountID.
@@ -92,66 +82,22 @@ func (accessInfo *AccessInfo) GetAccount(accountID *uuid.UUID) (*Account, error)
 
 // GetAccountHistory gets all AccountHistorys with a specified accountID.
 func (accessInfo *AccessInfo) GetAccountHistory(accountID *uuid.UUID) *AccountHistoryCollection {
-	accountHistoryCollection := AccountHistoryCollection{
-		pageableCollection: accessInfo.newPageableCollection(true),
-		id:                 accountID,
-	}
-	return &accountHistoryCollection
+	// GET /accounts/<account-id>/ledger
+	return newIterator[*AccountHistory](accessInfo, http.MethodGet, fmt.Sprintf("/accounts/%s/ledger", accountID), "", "", true)
 }
 
 // GetAccountHolds gets all AcountHolds with a specified accountID.
 func (accessInfo *AccessInfo) GetAccountHolds(accountID *uuid.UUID) *AccountHoldCollection {
-	accountHoldCollection := AccountHoldCollection{
-		pageableCollection: accessInfo.newPageableCollection(true),
-		id:                 accountID,
-	}
-	return &accountHoldCollection
-}
-
-// HasNext determines if there is another Account in this iterator.
-func (c *AccountCollection) HasNext() bool {
-	// GET /accounts
-	var accounts []Account
-	return c.pageableCollection.hasNext(http.MethodGet, "/accounts", "", "", &accounts)
-}
-
-// HasNext determines if there is another AccountHistory in this iterator.
-func (c *AccountHistoryCollection) HasNext() bool {
-	// GET /accounts/<account-id>
-	var accountHistory []AccountHistory
-	return c.pageableCollection.hasNext(http.MethodGet, fmt.Sprintf("/accounts/%s/ledger", c.id), "", "", &accountHistory)
-}
-
-// HasNext determines if there is another AccountHold in this iterator.
-func (c *AccountHoldCollection) HasNext() bool {
 	// GET /accounts/<account-id>/holds
-	var accountHolds []AccountHold
-	return c.pageableCollection.hasNext(http.MethodGet, fmt.Sprintf("/accounts/%s/holds", c.id), "", "", &accountHolds)
+	return newIterator[*AccountHold](accessInfo, http.MethodGet, fmt.Sprintf("/accounts/%s/holds", accountID), "", "", true)
 }
 
-// Next gets the next Account from the iterator.
-func (c *AccountCollection) Next() (*Account, error) {
-	account, err := c.pageableCollection.next()
-	if err != nil {
-		return nil, err
-	}
-	return account.Addr().Interface().(*Account), nil
+// timestamp gets the creation time of an AccountHistory.
+func (history *AccountHistory) timestamp() time.Time {
+	return history.CreatedAt
 }
 
-// Next gets the next AccountHistory from the iterator.
-func (c *AccountHistoryCollection) Next() (*AccountHistory, error) {
-	history, err := c.pageableCollection.next()
-	if err != nil {
-		return nil, err
-	}
-	return history.Addr().Interface().(*AccountHistory), nil
-}
-
-// Next gets the next AccountHold from the iterator.
-func (c *AccountHoldCollection) Next() (*AccountHold, error) {
-	hold, err := c.pageableCollection.next()
-	if err != nil {
-		return nil, err
-	}
-	return hold.Addr().Interface().(*AccountHold), nil
+// timestamp gets the creation time of an AccountHold.
+func (hold *AccountHold) timestamp() time.Time {
+	return hold.CreatedAt
 }
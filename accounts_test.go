@@ -1,6 +1,7 @@
 package gdax_test
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -124,9 +125,7 @@ func TestGetAccountsError(t *testing.T) {
 		BodyString(`{"message": "Account id not found"}`)
 
 	accounts := accessInfo.GetAccounts()
-	assert.True(accounts.HasNext())
-
-	account, err := accounts.Next()
+	account, err := accounts.Next(context.Background())
 	assert.Error(err)
 	assert.Nil(account)
 	assert.Equal(err.Error(), "Account id not found")
@@ -146,10 +145,10 @@ func TestGetAccounts(t *testing.T) {
 
 	var ids = [...]string{"71452118-efc7-4cc4-8780-a5e22d4baa53", "e316cb9a-0808-4fd7-8914-97829c1925de"}
 
-	for idx, accounts := 0, accessInfo.GetAccounts(); accounts.HasNext(); idx++ {
-		account, err := accounts.Next()
-		assert.NoError(err)
-
+	accounts, err := accessInfo.GetAccounts().Collect(context.Background())
+	assert.NoError(err)
+	assert.Len(accounts, len(ids))
+	for idx, account := range accounts {
 		parsedID, err := uuid.Parse(ids[idx])
 		assert.NoError(err)
 
@@ -218,9 +217,7 @@ func TestGetAccountHistoryError(t *testing.T) {
 	assert.NoError(err)
 
 	accountHistories := accessInfo.GetAccountHistory(&parsedAccountID)
-	assert.True(accountHistories.HasNext())
-
-	accountHistory, err := accountHistories.Next()
+	accountHistory, err := accountHistories.Next(context.Background())
 	assert.Error(err)
 	assert.Nil(accountHistory)
 	assert.Equal(err.Error(), "Account id not found")
@@ -257,9 +254,10 @@ func TestGetAccountHistory(t *testing.T) {
 	parsedAccountID, err := uuid.Parse(accountID)
 	assert.NoError(err)
 
-	for idx, accountHistories := 0, accessInfo.GetAccountHistory(&parsedAccountID); accountHistories.HasNext(); idx++ {
-		accountHistory, err := accountHistories.Next()
-		assert.NoError(err)
+	accountHistories, err := accessInfo.GetAccountHistory(&parsedAccountID).Collect(context.Background())
+	assert.NoError(err)
+	assert.Len(accountHistories, len(orderIDs))
+	for idx, accountHistory := range accountHistories {
 		t.Log(accountHistory)
 
 		parsedID, err := uuid.Parse(orderIDs[idx])
@@ -286,9 +284,7 @@ func TestGetAccountHoldsError(t *testing.T) {
 	assert.NoError(err)
 
 	accountHolds := accessInfo.GetAccountHolds(&parsedAccountID)
-	assert.True(accountHolds.HasNext())
-
-	accountHold, err := accountHolds.Next()
+	accountHold, err := accountHolds.Next(context.Background())
 	assert.Error(err)
 	assert.Nil(accountHold)
 	assert.Equal(err.Error(), "Account id not found")
@@ -326,9 +322,10 @@ func TestGetAccountHolds(t *testing.T) {
 	parsedAccountID, err := uuid.Parse(accountID)
 	assert.NoError(err)
 
-	for idx, accountHolds := 0, accessInfo.GetAccountHolds(&parsedAccountID); accountHolds.HasNext(); idx++ {
-		accountHold, err := accountHolds.Next()
-		assert.NoError(err)
+	accountHolds, err := accessInfo.GetAccountHolds(&parsedAccountID).Collect(context.Background())
+	assert.NoError(err)
+	assert.Len(accountHolds, len(ids))
+	for idx, accountHold := range accountHolds {
 		t.Log(accountHold)
 
 		parsedID, err := uuid.Parse(ids[idx])
@@ -338,3 +335,35 @@ func TestGetAccountHolds(t *testing.T) {
 		assert.Equal(accountHold.Amount, amounts[idx])
 	}
 }
+
+func TestGetAccountsRetry(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/accounts").
+		Reply(http.StatusServiceUnavailable).
+		BodyString(`{"message": "Service unavailable"}`)
+	gock.New(gdax.EndPoint).
+		Get("/accounts").
+		Reply(http.StatusOK).
+		BodyString(accountsJSON)
+
+	accounts := accessInfo.GetAccounts()
+	account, err := accounts.Next(context.Background())
+	assert.Error(err)
+	assert.Nil(account)
+
+	for idx := 0; idx < 2; idx++ {
+		account, err = accounts.Next(context.Background())
+		assert.NoError(err)
+		assert.NotNil(account)
+	}
+
+	account, err = accounts.Next(context.Background())
+	assert.Equal(err, gdax.ErrDone)
+	assert.Nil(account)
+}
@@ -0,0 +1,308 @@
+package gdax
+
+import (
+	"context"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultEquityInterval is how often a backtest samples the equity if no interval is specified.
+const DefaultEquityInterval = time.Hour
+
+// A Strategy reacts to market data by trading. In a backtest, it trades with a simulated Trader.
+type Strategy interface {
+	OnMessage(trader Trader, m Message)
+}
+
+// A StrategyFunc is a Strategy implemented by a function.
+type StrategyFunc func(trader Trader, m Message)
+
+// OnMessage calls the function.
+func (f StrategyFunc) OnMessage(trader Trader, m Message) {
+	f(trader, m)
+}
+
+// A MarketData is a chronological source of messages for a backtest.
+// Next gets the next message and the time it occurred, or io.EOF when there are no more messages.
+type MarketData interface {
+	Next() (Message, time.Time, error)
+}
+
+// A sliceData is a MarketData of messages that are already in memory.
+type sliceData struct {
+	messages []Message
+	times    []time.Time
+	index    int
+}
+
+// Next gets the next message.
+func (data *sliceData) Next() (Message, time.Time, error) {
+	if data.index >= len(data.messages) {
+		return nil, time.Time{}, io.EOF
+	}
+	data.index++
+	return data.messages[data.index-1], data.times[data.index-1], nil
+}
+
+// TradeData replays historic trades of a product (e.g., from GetTrades, which are newest first) as Matches, oldest
+// first.
+func TradeData(productID string, trades []*Trade) MarketData {
+	sorted := append([]*Trade(nil), trades...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].TradeID < sorted[j].TradeID })
+	data := sliceData{}
+	for _, trade := range sorted {
+		data.messages = append(data.messages, trade.Match(productID))
+		data.times = append(data.times, trade.timestamp())
+	}
+	return &data
+}
+
+// CandleData replays historic candles of a product (e.g., from GetHistoricRates) as Matches, oldest first.
+// Each candle of the specified granularity becomes four trades of a quarter of its volume, a quarter of the
+// granularity apart: the open, then the low and high (or, for a falling candle, the high and low), then the close.
+func CandleData(productID string, candles []Candle, granularity time.Duration) MarketData {
+	sorted := append([]Candle(nil), candles...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+	data := sliceData{}
+	var tradeID int64
+	for _, candle := range sorted {
+		prices := []float64{candle.Open, candle.Low, candle.High, candle.Close}
+		if candle.Close < candle.Open {
+			prices[1], prices[2] = candle.High, candle.Low
+		}
+		for idx, price := range prices {
+			tradeID++
+			at := candle.Time.Add(time.Duration(idx) * granularity / 4)
+			var match Match
+			match.Type = MatchType
+			match.ProductID = productID
+			match.TradeID = tradeID
+			match.Time = &at
+			match.Price = price
+			match.Size = candle.Volume / 4
+			data.messages = append(data.messages, match)
+			data.times = append(data.times, at)
+		}
+	}
+	return &data
+}
+
+// A recordingData replays the frames of a recording.
+type recordingData struct {
+	reader *RecordingReader
+}
+
+// RecordingData replays the frames of a recording (e.g., from a Recorder), at the time they were received.
+// Recordings of the level2 channel let orders trade against the recorded order book.
+func RecordingData(reader *RecordingReader) MarketData {
+	return recordingData{reader: reader}
+}
+
+// Next decodes the next frame.
+func (data recordingData) Next() (Message, time.Time, error) {
+	frame, err := data.reader.Next()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	message, err := decodeFrame(frame.Frame)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return message, frame.ReceivedAt, nil
+}
+
+// BacktestOptions configure a backtest.
+type BacktestOptions struct {
+	// Balances are the starting balances by currency (e.g., {"USD": 1000}).
+	Balances map[string]float64
+	// MakerFeeRate and TakerFeeRate are the fees charged in the quote currency, as fractions of the executed value.
+	MakerFeeRate float64
+	TakerFeeRate float64
+	// Latency is how long orders and cancellations take to reach the simulated exchange.
+	Latency time.Duration
+	// QuoteCurrency is the currency that the equity is valued in. If empty, "USD" is used.
+	// Other currencies are valued at the last trade price of their product in the quote currency.
+	QuoteCurrency string
+	// EquityInterval is how often the equity is sampled. If zero, DefaultEquityInterval is used.
+	// The Sharpe ratio is annualized from returns of this interval.
+	EquityInterval time.Duration
+}
+
+// An EquityPoint is the value of all balances at a point in time.
+type EquityPoint struct {
+	Time   time.Time
+	Equity float64
+}
+
+// A BacktestResult summarizes a backtest.
+type BacktestResult struct {
+	// Equity is the equity curve, sampled at the first message, every EquityInterval, and the last message.
+	Equity []EquityPoint
+	// Fills are the fills of the strategy's orders, oldest first.
+	Fills []Fill
+	// Fees is the total of the fees paid, and Volume is the total executed value, in the quote currency. Fills of
+	// products in other quote currencies are converted at the last trade price when they were filled; fills that
+	// cannot be converted then are not counted.
+	Fees   float64
+	Volume float64
+	// Turnover is the Volume as a multiple of the starting equity.
+	Turnover float64
+	// Return is the change of the equity as a fraction of the starting equity.
+	Return float64
+	// Sharpe is the annualized Sharpe ratio of the returns between equity samples, with a risk-free rate of zero.
+	Sharpe float64
+	// MaxDrawdown is the largest fall of the equity from a previous peak, as a fraction of the peak.
+	MaxDrawdown float64
+}
+
+// Backtest replays market data through a strategy, which trades with a simulated Trader that fills orders against
+// the replayed market as a PaperTrader does, with latency and queue position. The simulated clock is the time of
+// the message being replayed. Without a level2 book (e.g., when replaying trades or candles), orders trade at the
+// last trade price with unlimited size. Orders that have not arrived by the end of the data are never executed.
+func Backtest(ctx context.Context, data MarketData, strategy Strategy, options *BacktestOptions) (*BacktestResult, error) {
+	var config BacktestOptions
+	if options != nil {
+		config = *options
+	}
+	if config.QuoteCurrency == "" {
+		config.QuoteCurrency = "USD"
+	}
+	if config.EquityInterval <= 0 {
+		config.EquityInterval = DefaultEquityInterval
+	}
+
+	var clock time.Time
+	trader := NewPaperTrader(&PaperTraderOptions{
+		Balances:     config.Balances,
+		MakerFeeRate: config.MakerFeeRate,
+		TakerFeeRate: config.TakerFeeRate,
+		Latency:      config.Latency,
+		Now:          func() time.Time { return clock },
+	})
+	trader.synthesizeBooks = true
+
+	var (
+		result     BacktestResult
+		nextSample time.Time
+		sampled    bool
+		tallied    int
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		message, at, err := data.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		clock = at
+		trader.Handle(message)
+		strategy.OnMessage(trader, message)
+		tallied = trader.tally(&result, tallied, config.QuoteCurrency)
+		sampled = false
+		if nextSample.IsZero() || !at.Before(nextSample) {
+			result.Equity = append(result.Equity, EquityPoint{Time: at, Equity: trader.equity(config.QuoteCurrency)})
+			nextSample = at.Truncate(config.EquityInterval).Add(config.EquityInterval)
+			sampled = true
+		}
+	}
+	if !sampled && !clock.IsZero() {
+		result.Equity = append(result.Equity, EquityPoint{Time: clock, Equity: trader.equity(config.QuoteCurrency)})
+	}
+
+	trader.mu.Lock()
+	for _, fill := range trader.fills {
+		result.Fills = append(result.Fills, *fill)
+	}
+	trader.mu.Unlock()
+	result.summarize(config.EquityInterval)
+	return &result, nil
+}
+
+// summarize computes the return, turnover, Sharpe ratio, and maximum drawdown of the equity curve.
+func (result *BacktestResult) summarize(interval time.Duration) {
+	if len(result.Equity) == 0 || result.Equity[0].Equity <= 0 {
+		return
+	}
+	start := result.Equity[0].Equity
+	result.Return = result.Equity[len(result.Equity)-1].Equity/start - 1
+	result.Turnover = result.Volume / start
+
+	peak := start
+	var returns []float64
+	for idx, point := range result.Equity {
+		peak = math.Max(peak, point.Equity)
+		if peak > 0 {
+			result.MaxDrawdown = math.Max(result.MaxDrawdown, (peak-point.Equity)/peak)
+		}
+		if idx > 0 && result.Equity[idx-1].Equity > 0 {
+			returns = append(returns, point.Equity/result.Equity[idx-1].Equity-1)
+		}
+	}
+	if len(returns) < 2 {
+		return
+	}
+	var mean, variance float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+	if variance > 0 {
+		periodsPerYear := float64(365*24*time.Hour) / float64(interval)
+		result.Sharpe = mean / math.Sqrt(variance) * math.Sqrt(periodsPerYear)
+	}
+}
+
+// equity gets the value of all balances in the specified currency at the last trade prices.
+// Currencies without a product in (or from) the quote currency are not counted.
+func (trader *PaperTrader) equity(quote string) float64 {
+	trader.mu.Lock()
+	defer trader.mu.Unlock()
+	var equity float64
+	for _, account := range trader.accounts {
+		if rate, ok := trader.rate(account.Currency, quote); ok {
+			equity += account.Balance * rate
+		}
+	}
+	return equity
+}
+
+// rate gets the value of one unit of a currency in the quote currency from the last trade price of a product between
+// them. The caller must hold the lock.
+func (trader *PaperTrader) rate(currency, quote string) (float64, bool) {
+	if currency == quote {
+		return 1, true
+	}
+	if price, ok := trader.lastPrices[currency+"-"+quote]; ok {
+		return price, true
+	}
+	if price, ok := trader.lastPrices[quote+"-"+currency]; ok && price > 0 {
+		return 1 / price, true
+	}
+	return 0, false
+}
+
+// tally adds the fees and executed value of the fills after the first tallied fills to a result, converted to the
+// quote currency at the current rates, and returns the number of fills tallied.
+func (trader *PaperTrader) tally(result *BacktestResult, tallied int, quote string) int {
+	trader.mu.Lock()
+	defer trader.mu.Unlock()
+	for _, fill := range trader.fills[tallied:] {
+		_, fillQuote, _ := strings.Cut(fill.ProductID, "-")
+		if rate, ok := trader.rate(fillQuote, quote); ok {
+			result.Fees += fill.Fee * rate
+			result.Volume += fill.Price * fill.Size * rate
+		}
+	}
+	return len(trader.fills)
+}
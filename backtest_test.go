@@ -0,0 +1,166 @@
+package gdax_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+)
+
+// A replay is a MarketData of messages at fixed times.
+type replay struct {
+	messages []gdax.Message
+	times    []time.Time
+}
+
+// Next gets the next message.
+func (r *replay) Next() (gdax.Message, time.Time, error) {
+	if len(r.messages) == 0 {
+		return nil, time.Time{}, io.EOF
+	}
+	m, at := r.messages[0], r.times[0]
+	r.messages, r.times = r.messages[1:], r.times[1:]
+	return m, at, nil
+}
+
+func TestBacktestTrades(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	var trades []*gdax.Trade
+	for idx, price := range []float64{100, 90, 110, 120} {
+		at := start.Add(time.Duration(idx) * time.Minute)
+		trades = append(trades, &gdax.Trade{TradeID: int64(idx + 1), Time: &at, Price: price, Size: 1, Side: gdax.Sell})
+	}
+
+	placed := false
+	strategy := gdax.StrategyFunc(func(trader gdax.Trader, m gdax.Message) {
+		if placed {
+			return
+		}
+		placed = true
+		_, err := trader.PlaceMarketOrder(&gdax.Order{Side: gdax.Buy, ProductID: "BTC-USD", Size: 1})
+		assert.NoError(err)
+		_, err = trader.PlaceLimitOrder(&gdax.Order{Side: gdax.Sell, ProductID: "BTC-USD", Price: 115, Size: 1})
+		assert.NoError(err)
+	})
+	result, err := gdax.Backtest(context.Background(), gdax.TradeData("BTC-USD", trades), strategy, &gdax.BacktestOptions{
+		Balances:       map[string]float64{"USD": 1000},
+		TakerFeeRate:   0.01,
+		EquityInterval: time.Minute,
+	})
+	assert.NoError(err)
+
+	equity := make([]float64, len(result.Equity))
+	for idx, point := range result.Equity {
+		equity[idx] = point.Equity
+	}
+	assert.Equal(equity, []float64{999, 989, 1009, 1014})
+	assert.Len(result.Fills, 2)
+	assert.Equal(result.Fills[1].Price, 115.0)
+	assert.Equal(result.Fills[1].Liquidity, gdax.Maker)
+	assert.Equal(result.Fees, 1.0)
+	assert.Equal(result.Volume, 215.0)
+	assert.InDelta(result.Turnover, 215.0/999, 1e-9)
+	assert.InDelta(result.Return, 1014.0/999-1, 1e-9)
+	assert.InDelta(result.MaxDrawdown, 10.0/999, 1e-9)
+	assert.True(result.Sharpe > 0)
+}
+
+func TestBacktestLatencyAndQueue(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	var snapshot gdax.Snapshot
+	snapshot.Type = gdax.SnapshotType
+	snapshot.ProductID = "BTC-USD"
+	snapshot.Bids = []gdax.Bid{{Price: 99, Size: 2}}
+	snapshot.Asks = []gdax.Ask{{Price: 101, Size: 1}}
+	data := &replay{
+		messages: []gdax.Message{
+			snapshot,
+			match(1, start, 99, 1),   // before the order arrives
+			match(2, start, 99, 2),   // fills the queue ahead of the order
+			match(3, start, 99, 0.5), // fills the order at its price
+			match(4, start, 98, 5),   // trades through the order
+		},
+		times: []time.Time{start, start.Add(500 * time.Millisecond), start.Add(2 * time.Second), start.Add(3 * time.Second), start.Add(4 * time.Second)},
+	}
+
+	var order *gdax.Order
+	strategy := gdax.StrategyFunc(func(trader gdax.Trader, m gdax.Message) {
+		if order != nil {
+			return
+		}
+		var err error
+		order, err = trader.PlaceLimitOrder(&gdax.Order{Side: gdax.Buy, ProductID: "BTC-USD", Price: 99, Size: 1})
+		assert.NoError(err)
+		assert.Equal(order.Status, gdax.Pending)
+	})
+	result, err := gdax.Backtest(context.Background(), data, strategy, &gdax.BacktestOptions{
+		Balances: map[string]float64{"USD": 1000},
+		Latency:  time.Second,
+	})
+	assert.NoError(err)
+	assert.Len(result.Fills, 2)
+	for _, fill := range result.Fills {
+		assert.Equal(fill.Size, 0.5)
+		assert.Equal(fill.Price, 99.0)
+		assert.Equal(fill.OrderID, order.ID)
+	}
+}
+
+func TestCandleData(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := gdax.CandleData("BTC-USD", []gdax.Candle{{Time: start, Low: 90, High: 120, Open: 110, Close: 100, Volume: 8}}, time.Minute)
+	var prices []float64
+	for {
+		m, at, err := data.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(err)
+		match := m.(gdax.Match)
+		assert.Equal(match.Size, 2.0)
+		assert.Equal(at, *match.Time)
+		prices = append(prices, match.Price)
+	}
+	// a falling candle visits its high before its low.
+	assert.Equal(prices, []float64{110, 120, 90, 100})
+}
+
+func TestBacktestQuoteCurrencies(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	eth := match(2, start, 0.05, 1)
+	eth.ProductID = "ETH-BTC"
+	data := &replay{
+		messages: []gdax.Message{match(1, start, 10000, 1), eth},
+		times:    []time.Time{start, start.Add(time.Second)},
+	}
+	strategy := gdax.StrategyFunc(func(trader gdax.Trader, m gdax.Message) {
+		productID := m.(gdax.Match).ProductID
+		size := 1.0
+		if productID == "BTC-USD" {
+			size = 0.1
+		}
+		_, err := trader.PlaceMarketOrder(&gdax.Order{Side: gdax.Buy, ProductID: productID, Size: size})
+		assert.NoError(err)
+	})
+	result, err := gdax.Backtest(context.Background(), data, strategy, &gdax.BacktestOptions{
+		Balances:     map[string]float64{"USD": 2000, "BTC": 1},
+		TakerFeeRate: 0.01,
+	})
+	assert.NoError(err)
+	assert.Len(result.Fills, 2)
+
+	// the ETH-BTC fill is valued in USD at the BTC-USD price when it was filled.
+	assert.InDelta(result.Volume, 1000+0.05*10000, 1e-9)
+	assert.InDelta(result.Fees, 10+0.0005*10000, 1e-9)
+}
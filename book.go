@@ -0,0 +1,82 @@
+package gdax
+
+import "sort"
+
+// A priceLevel is the total size resting at a single price.
+type priceLevel struct {
+	price float64
+	size  float64
+}
+
+// An orderBook is the aggregated order book of a product, maintained from level2 snapshots and updates.
+// A synthetic book only quotes the last trade price of a product.
+type orderBook struct {
+	bids      map[float64]float64
+	asks      map[float64]float64
+	synthetic bool
+}
+
+// newOrderBook creates an empty orderBook.
+func newOrderBook() *orderBook {
+	return &orderBook{bids: make(map[float64]float64), asks: make(map[float64]float64)}
+}
+
+// apply updates the book with a Snapshot (which replaces it) or an L2Update. Other messages are ignored.
+func (book *orderBook) apply(m Message) {
+	switch m := m.(type) {
+	case Snapshot:
+		book.bids = make(map[float64]float64, len(m.Bids))
+		book.asks = make(map[float64]float64, len(m.Asks))
+		for _, bid := range m.Bids {
+			book.bids[bid.Price] = bid.Size
+		}
+		for _, ask := range m.Asks {
+			book.asks[ask.Price] = ask.Size
+		}
+	case L2Update:
+		for _, change := range m.Changes {
+			book.set(change.Side, change.Price, change.Size)
+		}
+	}
+}
+
+// side gets the levels of the side of the book that rests orders of the specified side.
+func (book *orderBook) side(side string) map[float64]float64 {
+	if side == Buy {
+		return book.bids
+	}
+	return book.asks
+}
+
+// set sets the size of a level, removing the level if the size is zero.
+func (book *orderBook) set(side string, price, size float64) {
+	if size <= 0 {
+		delete(book.side(side), price)
+		return
+	}
+	book.side(side)[price] = size
+}
+
+// levels gets the levels of one side of the book, best price first.
+func (book *orderBook) levels(side string) []priceLevel {
+	levels := make([]priceLevel, 0, len(book.side(side)))
+	for price, size := range book.side(side) {
+		levels = append(levels, priceLevel{price: price, size: size})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if side == Buy {
+			return levels[i].price > levels[j].price
+		}
+		return levels[i].price < levels[j].price
+	})
+	return levels
+}
+
+// best gets the best price of one side of the book.
+func (book *orderBook) best(side string) (float64, bool) {
+	levels := book.levels(side)
+	if len(levels) == 0 {
+		return 0, false
+	}
+	return levels[0].price, true
+}
@@ -0,0 +1,404 @@
+package gdax
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Historic rate limits
+const (
+	// maxCandlesPerRequest is the number of candles the exchange returns per request.
+	maxCandlesPerRequest = 300
+	// publicRequestsPerSecond is the rate limit of the exchange's public endpoints.
+	publicRequestsPerSecond = 3
+)
+
+// Granularities supported by the historic rates endpoint.
+var validGranularities = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+}
+
+// A Candle is a single OHLCV bar.
+// Time is the start of the bar's interval.
+type Candle struct {
+	Time   time.Time
+	Low    float64
+	High   float64
+	Open   float64
+	Close  float64
+	Volume float64
+	// Trades is the number of trades in the bar. It is zero for candles fetched with GetCandles.
+	Trades int
+}
+
+// UnmarshalJSON creates a Candle from the JSON array [time, low, high, open, close, volume] returned by the exchange.
+func (c *Candle) UnmarshalJSON(b []byte) error {
+	var fields []float64
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	if len(fields) < 6 {
+		return fmt.Errorf("malformed candle: %s", b)
+	}
+	c.Time = time.Unix(int64(fields[0]), 0).UTC()
+	c.Low, c.High, c.Open, c.Close, c.Volume = fields[1], fields[2], fields[3], fields[4], fields[5]
+	return nil
+}
+
+// GetCandles gets the historic candles of a product between start and end, oldest first.
+// The granularity must be one minute, five minutes, fifteen minutes, one hour, six hours, or one day, and the
+// exchange returns at most 300 candles per request; use GetHistoricRates for longer ranges.
+func (accessInfo *AccessInfo) GetCandles(productID string, start, end time.Time, granularity time.Duration) ([]Candle, error) {
+	// GET /products/<product-id>/candles
+	if !validGranularity(granularity) {
+		return nil, fmt.Errorf("unsupported candle granularity: %v", granularity)
+	}
+	return accessInfo.getCandles(context.Background(), productID, start, end, granularity)
+}
+
+// getCandles gets the historic candles of a product with a single request.
+func (accessInfo *AccessInfo) getCandles(ctx context.Context, productID string, start, end time.Time, granularity time.Duration) ([]Candle, error) {
+	params := url.Values{}
+	params.Set("start", start.UTC().Format(time.RFC3339))
+	params.Set("end", end.UTC().Format(time.RFC3339))
+	params.Set("granularity", fmt.Sprint(int64(granularity/time.Second)))
+	var candles []Candle
+	_, err := accessInfo.requestWithContext(ctx, http.MethodGet, fmt.Sprintf("/products/%s/candles?%s", productID, params.Encode()), "", &candles)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Time.Before(candles[j].Time) })
+	return candles, nil
+}
+
+// GetHistoricRates gets the historic candles of a product in [start, end), oldest first, without any duplicates.
+// The range is split into as many requests as needed, which are rate limited to the exchange's public rate limit.
+// Any granularity that is a multiple of one minute is supported: if the exchange does not support it directly,
+// candles of the coarsest supported granularity that evenly divides it are fetched and aggregated.
+// Like the exchange, intervals without any trades are omitted.
+func (accessInfo *AccessInfo) GetHistoricRates(ctx context.Context, productID string, start, end time.Time, granularity time.Duration) ([]Candle, error) {
+	fetchGranularity, ok := backfillGranularity(granularity)
+	if !ok {
+		return nil, fmt.Errorf("unsupported candle granularity: %v", granularity)
+	}
+	limiter := rateLimiter{interval: time.Second / publicRequestsPerSecond}
+	window := maxCandlesPerRequest * fetchGranularity
+	seen := make(map[int64]bool)
+	var candles []Candle
+	for windowStart := start.Truncate(fetchGranularity); windowStart.Before(end); windowStart = windowStart.Add(window) {
+		windowEnd := windowStart.Add(window - fetchGranularity)
+		if !windowEnd.Before(end) {
+			windowEnd = end
+		}
+		if err := limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+		page, err := accessInfo.getCandles(ctx, productID, windowStart, windowEnd, fetchGranularity)
+		if err != nil {
+			return nil, err
+		}
+		for _, candle := range page {
+			if candle.Time.Before(start) || !candle.Time.Before(end) || seen[candle.Time.Unix()] {
+				continue
+			}
+			seen[candle.Time.Unix()] = true
+			candles = append(candles, candle)
+		}
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Time.Before(candles[j].Time) })
+	if fetchGranularity != granularity {
+		candles = aggregateCandles(candles, granularity)
+	}
+	return candles, nil
+}
+
+// aggregateCandles combines sorted candles into candles of a coarser interval.
+func aggregateCandles(candles []Candle, interval time.Duration) []Candle {
+	var aggregated []Candle
+	for _, candle := range candles {
+		bucket := candle.Time.Truncate(interval)
+		if len(aggregated) == 0 || aggregated[len(aggregated)-1].Time.Before(bucket) {
+			aggregated = append(aggregated, Candle{Time: bucket, Low: candle.Low, High: candle.High, Open: candle.Open})
+		}
+		current := &aggregated[len(aggregated)-1]
+		current.Low = min(current.Low, candle.Low)
+		current.High = max(current.High, candle.High)
+		current.Close = candle.Close
+		current.Volume += candle.Volume
+		current.Trades += candle.Trades
+	}
+	return aggregated
+}
+
+// A rateLimiter spaces out sequential requests.
+type rateLimiter struct {
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the next request is allowed or the context is done.
+func (limiter *rateLimiter) wait(ctx context.Context) error {
+	if wait := time.Until(limiter.next); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	limiter.next = time.Now().Add(limiter.interval)
+	return nil
+}
+
+// validGranularity determines if the historic rates endpoint supports the specified granularity.
+func validGranularity(granularity time.Duration) bool {
+	for _, valid := range validGranularities {
+		if granularity == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// backfillGranularity finds the coarsest supported granularity that evenly divides the specified interval.
+func backfillGranularity(interval time.Duration) (time.Duration, bool) {
+	for idx := len(validGranularities) - 1; idx >= 0; idx-- {
+		if interval%validGranularities[idx] == 0 {
+			return validGranularities[idx], true
+		}
+	}
+	return 0, false
+}
+
+// CandleBuilderOptions configure a CandleBuilder.
+type CandleBuilderOptions struct {
+	// UseTickers also builds bars from Tickers, for subscriptions without the matches channel.
+	// A trade that arrives as both a Match and a Ticker is only counted once.
+	UseTickers bool
+	// SkipEmpty does not emit bars for intervals without any trades.
+	// Otherwise, an empty interval is emitted as a flat bar at the previous close with no volume.
+	SkipEmpty bool
+	// BufferSize is the number of closed bars buffered on the Candles channel. If zero, 64 is used.
+	BufferSize int
+}
+
+// A CandleBuilder aggregates the trades of a single product into OHLCV bars of a fixed interval.
+// A bar is closed, and sent on the Candles channel, once a trade of a later interval arrives or Advance passes its end.
+// Sending blocks when the channel is full, so the channel must be drained (or the builder closed).
+type CandleBuilder struct {
+	productID string
+	interval  time.Duration
+	options   CandleBuilderOptions
+	candles   chan Candle
+	closing   chan struct{}
+	closeOnce sync.Once
+
+	// sendMu serializes sending the closed bars, so that they are sent in order.
+	sendMu sync.Mutex
+	closed bool
+
+	mu          sync.Mutex
+	pending     []Candle
+	current     *Candle
+	next        time.Time
+	lastClose   float64
+	started     bool
+	lastTradeID int64
+	ignoreUntil time.Time
+}
+
+// NewCandleBuilder creates a CandleBuilder for the specified product and interval.
+func NewCandleBuilder(productID string, interval time.Duration, options *CandleBuilderOptions) *CandleBuilder {
+	builder := CandleBuilder{productID: productID, interval: interval}
+	if options != nil {
+		builder.options = *options
+	}
+	if builder.options.BufferSize <= 0 {
+		builder.options.BufferSize = 64
+	}
+	builder.candles = make(chan Candle, builder.options.BufferSize)
+	builder.closing = make(chan struct{})
+	return &builder
+}
+
+// Candles gets the channel of closed bars.
+// The channel is closed by Close.
+func (builder *CandleBuilder) Candles() <-chan Candle {
+	return builder.candles
+}
+
+// Handle adds the trade of a Match (or, with UseTickers, a Ticker) of the builder's product to the current bar.
+// Every other message is ignored, so Handle can be passed directly to Feed.
+func (builder *CandleBuilder) Handle(m Message) {
+	switch m := m.(type) {
+	case Match:
+		if m.ProductID == builder.productID && m.Time != nil {
+			builder.addTrade(m.TradeID, *m.Time, m.Price, m.Size)
+			builder.flush()
+		}
+	case Ticker:
+		if builder.options.UseTickers && m.ProductID == builder.productID && m.Time != nil {
+			builder.addTrade(m.TradeID, *m.Time, m.Price, m.LastSize)
+			builder.flush()
+		}
+	}
+}
+
+// Advance closes the current bar, and emits any empty bars, if the specified time is past their interval.
+// It should be called periodically so that bars are closed even when there are no trades.
+func (builder *CandleBuilder) Advance(now time.Time) {
+	builder.mu.Lock()
+	builder.advanceTo(now)
+	builder.mu.Unlock()
+	builder.flush()
+}
+
+// Backfill emits historic candles so that the series has no hole before the first live trade.
+// The candles may be of the builder's interval or of any interval that evenly divides it; they are aggregated as needed.
+// Trades before until (typically, the time the candles were fetched) are assumed to be included in the candles and
+// are ignored. The interval containing until is left open so that live trades are added to it.
+func (builder *CandleBuilder) Backfill(candles []Candle, until time.Time) {
+	sorted := append([]Candle(nil), candles...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	builder.mu.Lock()
+	for _, candle := range sorted {
+		bucket := candle.Time.Truncate(builder.interval)
+		if builder.current != nil && bucket.Before(builder.current.Time) || builder.started && builder.current == nil && bucket.Before(builder.next) {
+			continue
+		}
+		if builder.current == nil || bucket.After(builder.current.Time) {
+			builder.closeThrough(bucket)
+			builder.current = &Candle{Time: bucket, Low: candle.Low, High: candle.High, Open: candle.Open}
+			builder.started = true
+		}
+		builder.current.Low = min(builder.current.Low, candle.Low)
+		builder.current.High = max(builder.current.High, candle.High)
+		builder.current.Close = candle.Close
+		builder.current.Volume += candle.Volume
+		builder.current.Trades += candle.Trades
+		builder.lastClose = candle.Close
+	}
+	if until.After(builder.ignoreUntil) {
+		builder.ignoreUntil = until
+	}
+	builder.advanceTo(until)
+	builder.mu.Unlock()
+	builder.flush()
+}
+
+// BackfillFrom fetches the candles of the builder's product since the specified time and backfills them.
+// The builder's interval must be a multiple of one minute.
+func (builder *CandleBuilder) BackfillFrom(ctx context.Context, accessInfo *AccessInfo, start time.Time) error {
+	until := time.Now()
+	candles, err := accessInfo.GetHistoricRates(ctx, builder.productID, start, until, builder.interval)
+	if err != nil {
+		return err
+	}
+	builder.Backfill(candles, until)
+	return nil
+}
+
+// Close closes the Candles channel. The current, unfinished bar is not emitted, and closed bars that could not be
+// sent yet are discarded, so that any Handle, Advance, or Backfill that is blocked on a full channel returns.
+func (builder *CandleBuilder) Close() {
+	builder.closeOnce.Do(func() {
+		close(builder.closing)
+		builder.sendMu.Lock()
+		defer builder.sendMu.Unlock()
+		builder.closed = true
+		close(builder.candles)
+	})
+}
+
+// flush sends the closed bars on the Candles channel, without holding the lock of the bars.
+func (builder *CandleBuilder) flush() {
+	builder.sendMu.Lock()
+	defer builder.sendMu.Unlock()
+	for {
+		builder.mu.Lock()
+		pending := builder.pending
+		builder.pending = nil
+		builder.mu.Unlock()
+		if len(pending) == 0 || builder.closed {
+			return
+		}
+		for _, candle := range pending {
+			select {
+			case builder.candles <- candle:
+			case <-builder.closing:
+				return
+			}
+		}
+	}
+}
+
+// addTrade adds a single trade to the current bar, closing any bars before it.
+// Trades of intervals that were already closed, and trades that were already counted, are ignored.
+func (builder *CandleBuilder) addTrade(tradeID int64, at time.Time, price, size float64) {
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+	if tradeID != 0 {
+		if tradeID <= builder.lastTradeID {
+			return
+		}
+		builder.lastTradeID = tradeID
+	}
+	if at.Before(builder.ignoreUntil) {
+		return
+	}
+	bucket := at.Truncate(builder.interval)
+	if builder.current != nil && bucket.Before(builder.current.Time) || builder.started && builder.current == nil && bucket.Before(builder.next) {
+		return
+	}
+	if builder.current == nil || bucket.After(builder.current.Time) {
+		builder.closeThrough(bucket)
+		builder.current = &Candle{Time: bucket, Low: price, High: price, Open: price}
+		builder.started = true
+	}
+	builder.current.Low = min(builder.current.Low, price)
+	builder.current.High = max(builder.current.High, price)
+	builder.current.Close = price
+	builder.current.Volume += size
+	builder.current.Trades++
+	builder.lastClose = price
+}
+
+// advanceTo closes every bar before the interval containing the specified time.
+func (builder *CandleBuilder) advanceTo(now time.Time) {
+	if !builder.started {
+		return
+	}
+	bucket := now.Truncate(builder.interval)
+	if builder.current != nil && bucket.After(builder.current.Time) || builder.current == nil && bucket.After(builder.next) {
+		builder.closeThrough(bucket)
+	}
+}
+
+// closeThrough emits the current bar and an empty bar for every interval before the specified interval.
+// The bars are sent by the next flush.
+func (builder *CandleBuilder) closeThrough(bucket time.Time) {
+	if builder.current != nil {
+		builder.pending = append(builder.pending, *builder.current)
+		builder.next = builder.current.Time.Add(builder.interval)
+		builder.current = nil
+	}
+	if builder.started && !builder.options.SkipEmpty {
+		for t := builder.next; t.Before(bucket); t = t.Add(builder.interval) {
+			builder.pending = append(builder.pending, Candle{Time: t, Low: builder.lastClose, High: builder.lastClose, Open: builder.lastClose, Close: builder.lastClose})
+		}
+	}
+	builder.next = bucket
+}
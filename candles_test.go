@@ -0,0 +1,240 @@
+package gdax_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+const candlesJSON = `
+	[
+	    [1415398800, 0.32, 4.2, 0.35, 4.2, 12.3],
+	    [1415398740, 0.31, 0.33, 0.32, 0.32, 5.1]
+	]
+`
+
+// match creates a Match of BTC-USD.
+func match(tradeID int64, at time.Time, price, size float64) gdax.Match {
+	var m gdax.Match
+	m.Type = gdax.MatchType
+	m.ProductID = "BTC-USD"
+	m.TradeID = tradeID
+	m.Time = &at
+	m.Price = price
+	m.Size = size
+	return m
+}
+
+func TestGetCandles(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/products/BTC-USD/candles").
+		MatchParam("granularity", "60").
+		MatchParam("start", "2014-11-07T22:19:00Z").
+		Reply(http.StatusOK).
+		BodyString(candlesJSON)
+
+	start := time.Date(2014, 11, 7, 22, 19, 0, 0, time.UTC)
+	candles, err := accessInfo.GetCandles("BTC-USD", start, start.Add(2*time.Minute), time.Minute)
+	assert.NoError(err)
+	assert.Equal(candles, []gdax.Candle{
+		{Time: start, Low: 0.31, High: 0.33, Open: 0.32, Close: 0.32, Volume: 5.1},
+		{Time: start.Add(time.Minute), Low: 0.32, High: 4.2, Open: 0.35, Close: 4.2, Volume: 12.3},
+	})
+
+	_, err = accessInfo.GetCandles("BTC-USD", start, start.Add(time.Hour), 2*time.Minute)
+	assert.Error(err)
+}
+
+func TestCandleBuilder(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Date(2019, 8, 14, 20, 0, 0, 0, time.UTC)
+	builder := gdax.NewCandleBuilder("BTC-USD", time.Minute, nil)
+	builder.Handle(match(1, start.Add(5*time.Second), 100, 1))
+	builder.Handle(match(2, start.Add(20*time.Second), 110, 2))
+	builder.Handle(match(2, start.Add(20*time.Second), 110, 2))
+	builder.Handle(match(3, start.Add(50*time.Second), 90, 1))
+	// the next trade is two intervals later, so the interval in between is empty.
+	builder.Handle(match(4, start.Add(2*time.Minute+time.Second), 95, 3))
+	builder.Advance(start.Add(3 * time.Minute))
+	builder.Close()
+
+	var candles []gdax.Candle
+	for candle := range builder.Candles() {
+		candles = append(candles, candle)
+	}
+	assert.Equal(candles, []gdax.Candle{
+		{Time: start, Low: 90, High: 110, Open: 100, Close: 90, Volume: 4, Trades: 3},
+		{Time: start.Add(time.Minute), Low: 90, High: 90, Open: 90, Close: 90},
+		{Time: start.Add(2 * time.Minute), Low: 95, High: 95, Open: 95, Close: 95, Volume: 3, Trades: 1},
+	})
+}
+
+func TestCandleBuilderCloseWhenFull(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Date(2019, 8, 14, 20, 0, 0, 0, time.UTC)
+	builder := gdax.NewCandleBuilder("BTC-USD", time.Minute, &gdax.CandleBuilderOptions{BufferSize: 1})
+	handled := make(chan struct{})
+	go func() {
+		defer close(handled)
+		builder.Handle(match(1, start, 100, 1))
+		// the second trade closes two bars, but only one fits in the buffer.
+		builder.Handle(match(2, start.Add(2*time.Minute), 101, 1))
+	}()
+	select {
+	case <-handled:
+		t.Fatal("Handle did not block on a full buffer")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		builder.Close()
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked on a full buffer")
+	}
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Handle did not return after Close")
+	}
+
+	var times []time.Time
+	for candle := range builder.Candles() {
+		times = append(times, candle.Time)
+	}
+	assert.Equal(times, []time.Time{start})
+	// handling trades after closing neither blocks nor panics.
+	builder.Handle(match(3, start.Add(5*time.Minute), 102, 1))
+}
+
+func TestCandleBuilderSkipEmpty(t *testing.T) {
+	start := time.Date(2019, 8, 14, 20, 0, 0, 0, time.UTC)
+	builder := gdax.NewCandleBuilder("BTC-USD", time.Minute, &gdax.CandleBuilderOptions{SkipEmpty: true})
+	builder.Handle(match(1, start, 100, 1))
+	builder.Handle(match(2, start.Add(5*time.Minute), 101, 1))
+	builder.Advance(start.Add(10 * time.Minute))
+	builder.Close()
+
+	var times []time.Time
+	for candle := range builder.Candles() {
+		times = append(times, candle.Time)
+	}
+	assert.Equal(t, times, []time.Time{start, start.Add(5 * time.Minute)})
+}
+
+func TestCandleBuilderBackfill(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	start := time.Date(2014, 11, 7, 22, 18, 0, 0, time.UTC)
+	builder := gdax.NewCandleBuilder("BTC-USD", 2*time.Minute, nil)
+	builder.Backfill([]gdax.Candle{
+		{Time: start.Add(time.Minute), Low: 0.32, High: 4.2, Open: 0.35, Close: 4.2, Volume: 12.3},
+		{Time: start, Low: 0.31, High: 0.33, Open: 0.32, Close: 0.32, Volume: 5.1},
+		{Time: start.Add(2 * time.Minute), Low: 4, High: 4.1, Open: 4.1, Close: 4, Volume: 1},
+	}, start.Add(2*time.Minute+30*time.Second))
+	// this trade was already counted in the backfilled candles.
+	builder.Handle(match(1, start.Add(2*time.Minute+10*time.Second), 4, 1))
+	builder.Handle(match(2, start.Add(2*time.Minute+40*time.Second), 5, 2))
+	builder.Advance(start.Add(4 * time.Minute))
+	builder.Close()
+
+	var candles []gdax.Candle
+	for candle := range builder.Candles() {
+		candles = append(candles, candle)
+	}
+	assert.Len(candles, 2)
+	assert.Equal(candles[0], gdax.Candle{Time: start, Low: 0.31, High: 4.2, Open: 0.32, Close: 4.2, Volume: 17.4})
+	assert.Equal(candles[1], gdax.Candle{Time: start.Add(2 * time.Minute), Low: 4, High: 5, Open: 4.1, Close: 5, Volume: 3, Trades: 1})
+
+	err = gdax.NewCandleBuilder("BTC-USD", 90*time.Second, nil).BackfillFrom(context.Background(), accessInfo, start)
+	assert.Error(err)
+}
+
+func TestGetHistoricRates(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	start := time.Date(2014, 11, 7, 0, 0, 0, 0, time.UTC)
+	// 450 minutes need two requests of at most 300 candles each.
+	windows := []struct {
+		start, end time.Time
+		candles    []time.Time
+	}{
+		{start, start.Add(299 * time.Minute), []time.Time{start.Add(3 * time.Minute), start, start.Add(time.Minute)}},
+		{start.Add(300 * time.Minute), start.Add(450 * time.Minute), []time.Time{start.Add(450 * time.Minute), start.Add(301 * time.Minute), start.Add(time.Minute)}},
+	}
+	for _, window := range windows {
+		var rows []string
+		for _, at := range window.candles {
+			rows = append(rows, fmt.Sprintf("[%d, 1, 3, 2, 2.5, 10]", at.Unix()))
+		}
+		gock.New(gdax.EndPoint).
+			Get("/products/BTC-USD/candles").
+			MatchParam("start", window.start.Format(time.RFC3339)).
+			MatchParam("end", window.end.Format(time.RFC3339)).
+			MatchParam("granularity", "60").
+			Reply(http.StatusOK).
+			BodyString("[" + strings.Join(rows, ",") + "]")
+	}
+
+	candles, err := accessInfo.GetHistoricRates(context.Background(), "BTC-USD", start, start.Add(450*time.Minute), time.Minute)
+	assert.NoError(err)
+	var times []time.Time
+	for _, candle := range candles {
+		times = append(times, candle.Time)
+	}
+	assert.Equal(times, []time.Time{start, start.Add(time.Minute), start.Add(3 * time.Minute), start.Add(301 * time.Minute)})
+	assert.True(gock.IsDone())
+}
+
+func TestGetHistoricRatesAggregates(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	start := time.Date(2014, 11, 7, 0, 0, 0, 0, time.UTC)
+	gock.New(gdax.EndPoint).
+		Get("/products/BTC-USD/candles").
+		MatchParam("granularity", "60").
+		Reply(http.StatusOK).
+		BodyString(fmt.Sprintf("[[%d, 1, 3, 2, 2.5, 10], [%d, 0.5, 2, 1.5, 1, 5], [%d, 2, 4, 3, 4, 1]]",
+			start.Add(2*time.Minute).Unix(), start.Add(time.Minute).Unix(), start.Unix()))
+
+	candles, err := accessInfo.GetHistoricRates(context.Background(), "BTC-USD", start, start.Add(4*time.Minute), 2*time.Minute)
+	assert.NoError(err)
+	assert.Equal(candles, []gdax.Candle{
+		{Time: start, Low: 0.5, High: 4, Open: 3, Close: 1, Volume: 6},
+		{Time: start.Add(2 * time.Minute), Low: 1, High: 3, Open: 2, Close: 2.5, Volume: 10},
+	})
+
+	_, err = accessInfo.GetHistoricRates(context.Background(), "BTC-USD", start, start.Add(time.Hour), 90*time.Second)
+	assert.Error(err)
+}
@@ -0,0 +1,223 @@
+package gdax
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is the timeout of the HTTP client of an AccessInfo if no timeout or client is specified.
+const DefaultTimeout = 30 * time.Second
+
+// A Middleware wraps the transport of the HTTP client of an AccessInfo.
+// It sees every request before it is sent and every response before it is parsed, so it can add tracing,
+// record metrics, or inject faults.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// A RoundTripperFunc is an http.RoundTripper implemented by a function, which is convenient for writing Middleware.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls the function.
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// A ClientOption configures the HTTP client of an AccessInfo.
+type ClientOption func(*clientConfig) error
+
+// A clientConfig is the configuration built by ClientOptions.
+type clientConfig struct {
+	client      *http.Client
+	timeout     time.Duration
+	timeoutSet  bool
+	proxy       func(*http.Request) (*url.URL, error)
+	userAgent   string
+	headers     http.Header
+	middleware  []Middleware
+	logger      Logger
+	redact      bool
+	clockSync   time.Duration
+	credentials CredentialProvider
+	signer      Signer
+	endPoint    string
+}
+
+// WithHTTPClient uses the specified HTTP client instead of a new one.
+// The client is copied, so later options do not modify it.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(config *clientConfig) error {
+		if client == nil {
+			return errors.New("the HTTP client must not be nil")
+		}
+		config.client = client
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of every request, including reading the response.
+// A timeout of zero means no timeout.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(config *clientConfig) error {
+		config.timeout = timeout
+		config.timeoutSet = true
+		return nil
+	}
+}
+
+// WithProxy sends every request through the proxy at the specified URL.
+func WithProxy(proxyURL string) ClientOption {
+	return func(config *clientConfig) error {
+		parsed, err := url.Parse(proxyURL)
+		if err != nil {
+			return err
+		}
+		config.proxy = http.ProxyURL(parsed)
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(config *clientConfig) error {
+		config.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithHeader adds a header to every request.
+// The authentication headers cannot be overridden.
+func WithHeader(key, value string) ClientOption {
+	return func(config *clientConfig) error {
+		if config.headers == nil {
+			config.headers = make(http.Header)
+		}
+		config.headers.Add(key, value)
+		return nil
+	}
+}
+
+// WithMiddleware wraps the transport of the HTTP client with the specified middleware.
+// The first middleware is the outermost, so it sees requests first and responses last.
+func WithMiddleware(middleware ...Middleware) ClientOption {
+	return func(config *clientConfig) error {
+		config.middleware = append(config.middleware, middleware...)
+		return nil
+	}
+}
+
+// WithEndPoint sends every request to the REST API at the specified URL instead of EndPoint
+// (e.g., the production API or a gdaxtest.Exchange).
+func WithEndPoint(endPoint string) ClientOption {
+	return func(config *clientConfig) error {
+		parsed, err := url.Parse(endPoint)
+		if err != nil {
+			return err
+		}
+		if parsed.Scheme == "" || parsed.Host == "" {
+			return errors.New("the endpoint must be an absolute URL")
+		}
+		config.endPoint = strings.TrimSuffix(endPoint, "/")
+		return nil
+	}
+}
+
+// WithLogger writes log entries about every request to the specified Logger; by default, nothing is logged.
+// Credential headers are always redacted.
+func WithLogger(logger Logger) ClientOption {
+	return func(config *clientConfig) error {
+		config.logger = logger
+		return nil
+	}
+}
+
+// WithRedactedBodies also redacts request and response bodies from log entries.
+func WithRedactedBodies() ClientOption {
+	return func(config *clientConfig) error {
+		config.redact = true
+		return nil
+	}
+}
+
+// NewAccessInfo creates an AccessInfo with the specified credentials and an HTTP client configured by the options.
+func NewAccessInfo(publicKey, privateKey, passphrase string, options ...ClientOption) (*AccessInfo, error) {
+	accessInfo := AccessInfo{
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+		Passphrase: passphrase,
+	}
+	if err := accessInfo.configure(options); err != nil {
+		return nil, err
+	}
+	return &accessInfo, nil
+}
+
+// configure builds the HTTP client of an AccessInfo from the specified options.
+func (accessInfo *AccessInfo) configure(options []ClientOption) error {
+	config := clientConfig{timeout: DefaultTimeout}
+	for _, option := range options {
+		if err := option(&config); err != nil {
+			return err
+		}
+	}
+
+	var client http.Client
+	if config.client != nil {
+		client = *config.client
+		if config.timeoutSet {
+			client.Timeout = config.timeout
+		}
+	} else {
+		client.Timeout = config.timeout
+	}
+
+	transport := client.Transport
+	if config.proxy != nil {
+		base := transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		httpTransport, ok := base.(*http.Transport)
+		if !ok {
+			return errors.New("a proxy can only be set on an *http.Transport")
+		}
+		httpTransport = httpTransport.Clone()
+		httpTransport.Proxy = config.proxy
+		transport = httpTransport
+	}
+	if len(config.middleware) > 0 && transport == nil {
+		transport = defaultTransport{}
+	}
+	for idx := len(config.middleware) - 1; idx >= 0; idx-- {
+		transport = config.middleware[idx](transport)
+	}
+	client.Transport = transport
+
+	accessInfo.Client = &client
+	accessInfo.userAgent = config.userAgent
+	accessInfo.headers = config.headers
+	accessInfo.log = config.logger
+	accessInfo.redactBodies = config.redact
+	accessInfo.clock = &serverClock{interval: config.clockSync}
+	accessInfo.credentials = config.credentials
+	accessInfo.signer = config.signer
+	accessInfo.endPoint = config.endPoint
+	return nil
+}
+
+// baseURL gets the URL of the REST API of an AccessInfo, which is EndPoint if none was specified.
+func (accessInfo *AccessInfo) baseURL() string {
+	if accessInfo.endPoint == "" {
+		return EndPoint
+	}
+	return accessInfo.endPoint
+}
+
+// A defaultTransport sends requests with http.DefaultTransport, looked up at the time of the request.
+type defaultTransport struct{}
+
+// RoundTrip sends a request with http.DefaultTransport.
+func (defaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,179 @@
+package gdax_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func TestNewAccessInfoDefaults(t *testing.T) {
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.NewAccessInfo("public", "", "passphrase")
+	assert.NoError(err)
+	assert.Equal(accessInfo.Client.Timeout, gdax.DefaultTimeout)
+	assert.Nil(accessInfo.Client.Transport)
+
+	accessInfo, err = gdax.NewAccessInfo("public", "", "passphrase", gdax.WithTimeout(time.Second))
+	assert.NoError(err)
+	assert.Equal(accessInfo.Client.Timeout, time.Second)
+}
+
+func TestClientOptions(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	var order []string
+	trace := func(name string) gdax.Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return gdax.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name+" request")
+				resp, err := next.RoundTrip(req)
+				order = append(order, name+" response")
+				return resp, err
+			})
+		}
+	}
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables(
+		gdax.WithUserAgent("trading-bot/1.0"),
+		gdax.WithHeader("X-Request-Source", "tests"),
+		gdax.WithMiddleware(trace("outer"), trace("inner")),
+	)
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/fees").
+		MatchHeader("User-Agent", "trading-bot/1.0").
+		MatchHeader("X-Request-Source", "tests").
+		Reply(http.StatusOK).
+		BodyString(feesJSON)
+
+	fees, err := accessInfo.GetFees()
+	assert.NoError(err)
+	assert.Equal(fees.MakerFeeRate, 0.0015)
+	assert.Equal(order, []string{"outer request", "inner request", "inner response", "outer response"})
+}
+
+func TestClientMiddlewareFault(t *testing.T) {
+	fault := errors.New("injected fault")
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables(gdax.WithMiddleware(func(http.RoundTripper) http.RoundTripper {
+		return gdax.RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return nil, fault
+		})
+	}))
+	assert.NoError(t, err)
+
+	_, err = accessInfo.GetFees()
+	assert.ErrorIs(t, err, fault)
+}
+
+func TestClientProxy(t *testing.T) {
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables(gdax.WithProxy("http://proxy.internal:3128"))
+	assert.NoError(err)
+	transport, ok := accessInfo.Client.Transport.(*http.Transport)
+	assert.True(ok)
+	req, err := http.NewRequest(http.MethodGet, gdax.EndPoint, nil)
+	assert.NoError(err)
+	proxy, err := transport.Proxy(req)
+	assert.NoError(err)
+	assert.Equal(proxy.String(), "http://proxy.internal:3128")
+
+	custom := &http.Client{Transport: gdax.RoundTripperFunc(http.DefaultTransport.RoundTrip)}
+	_, err = gdax.NewAccessInfo("", "", "", gdax.WithHTTPClient(custom), gdax.WithProxy("http://proxy.internal:3128"))
+	assert.Error(err)
+}
+
+func TestClientEndPoint(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables(gdax.WithEndPoint("https://api.gdax.com/"))
+	assert.NoError(err)
+
+	gock.New("https://api.gdax.com").
+		Get("/fees").
+		Reply(http.StatusOK).
+		BodyString(feesJSON)
+
+	_, err = accessInfo.GetFees()
+	assert.NoError(err)
+	assert.True(gock.IsDone())
+
+	_, err = gdax.NewAccessInfo("", "", "", gdax.WithEndPoint("/relative"))
+	assert.Error(err)
+}
+
+func TestClientLogger(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	accessInfo, err := gdax.NewAccessInfo("my-public-key", "c2VjcmV0", "my-passphrase", gdax.WithLogger(gdax.NewSlogLogger(logger)))
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/fees").
+		Reply(http.StatusOK).
+		BodyString(feesJSON)
+
+	_, err = accessInfo.GetFees()
+	assert.NoError(err)
+	assert.Contains(logs.String(), "sending request")
+	assert.Contains(logs.String(), "status=200")
+	assert.Contains(logs.String(), "maker_fee_rate")
+	assert.Contains(logs.String(), "[REDACTED]")
+	assert.NotContains(logs.String(), "my-public-key")
+	assert.NotContains(logs.String(), "my-passphrase")
+}
+
+func TestClientLoggerRedactedBodies(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables(gdax.WithLogger(gdax.NewSlogLogger(logger)), gdax.WithRedactedBodies())
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/fees").
+		Reply(http.StatusOK).
+		BodyString(feesJSON)
+
+	_, err = accessInfo.GetFees()
+	assert.NoError(err)
+	assert.Contains(logs.String(), "received response")
+	assert.NotContains(logs.String(), "maker_fee_rate")
+}
+
+func TestClientSilentByDefault(t *testing.T) {
+	defer gock.Off()
+	defer log.SetOutput(os.Stderr)
+	assert := assert.New(t)
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/fees").
+		Reply(http.StatusOK).
+		BodyString(feesJSON)
+
+	_, err = accessInfo.GetFees()
+	assert.NoError(err)
+	assert.Empty(logs.String())
+}
@@ -0,0 +1,170 @@
+package gdax
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultClockSyncInterval is how often the server clock is resynchronized if no interval is specified.
+const DefaultClockSyncInterval = 5 * time.Minute
+
+// A ServerTime is the current time of the exchange.
+type ServerTime struct {
+	ISO   *time.Time `json:"iso,string"`
+	Epoch float64    `json:"epoch"`
+}
+
+// Time gets the server time with sub-second precision.
+func (serverTime *ServerTime) Time() time.Time {
+	seconds, fraction := math.Modf(serverTime.Epoch)
+	return time.Unix(int64(seconds), int64(fraction*1e9))
+}
+
+// A serverClock tracks the offset between the local clock and the exchange's clock.
+type serverClock struct {
+	mu       sync.Mutex
+	interval time.Duration
+	offset   time.Duration
+	rtt      time.Duration
+	syncedAt time.Time
+	syncing  bool
+}
+
+// clocksMu guards the creation of the clock of an AccessInfo that was not created by NewAccessInfo (or another
+// constructor), which is created when it is first used.
+var clocksMu sync.Mutex
+
+// A skipClockSync is a context key marking requests that must not trigger a clock synchronization.
+type skipClockSync struct{}
+
+// WithClockSync corrects the timestamps of signed requests and subscriptions for the skew between the local clock
+// and the exchange's clock. The offset is measured with GET /time before the first request and then refreshed
+// lazily, by the first request after it is older than the specified interval (or DefaultClockSyncInterval, if zero).
+// To refresh the offset in the background instead, use RunClockSync.
+func WithClockSync(interval time.Duration) ClientOption {
+	return func(config *clientConfig) error {
+		if interval <= 0 {
+			interval = DefaultClockSyncInterval
+		}
+		config.clockSync = interval
+		return nil
+	}
+}
+
+// GetTime gets the current time of the exchange.
+func (accessInfo *AccessInfo) GetTime() (*ServerTime, error) {
+	return accessInfo.getTime(context.Background())
+}
+
+// getTime gets the current time of the exchange without synchronizing the clock first.
+func (accessInfo *AccessInfo) getTime(ctx context.Context) (*ServerTime, error) {
+	// GET /time
+	var serverTime ServerTime
+	_, err := accessInfo.requestWithContext(context.WithValue(ctx, skipClockSync{}, true), http.MethodGet, "/time", "", &serverTime)
+	if err != nil {
+		return nil, err
+	}
+	return &serverTime, nil
+}
+
+// SyncClock measures the offset between the local clock and the exchange's clock, assuming that the request and the
+// response took equally long, and applies it to the timestamps of signed requests and subscriptions.
+// This function returns the offset (positive if the exchange's clock is ahead) and the round-trip latency.
+func (accessInfo *AccessInfo) SyncClock(ctx context.Context) (time.Duration, time.Duration, error) {
+	sent := time.Now()
+	serverTime, err := accessInfo.getTime(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	received := time.Now()
+	rtt := received.Sub(sent)
+	offset := serverTime.Time().Sub(sent.Add(rtt / 2))
+
+	clock := accessInfo.serverClock()
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.offset = offset
+	clock.rtt = rtt
+	clock.syncedAt = received
+	return offset, rtt, nil
+}
+
+// RunClockSync synchronizes the clock (as SyncClock does) immediately and then every interval (or
+// DefaultClockSyncInterval, if zero) until the context is done, and returns the context's error.
+// Failed synchronizations are logged and keep the previous offset. This function blocks, so it is typically run in
+// its own goroutine and stopped by canceling the context.
+func (accessInfo *AccessInfo) RunClockSync(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultClockSyncInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, _, err := accessInfo.SyncClock(ctx); err != nil && ctx.Err() == nil {
+			accessInfo.logger().Log(ctx, LevelWarn, "clock synchronization failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ClockOffset gets the offset and round-trip latency measured by the last clock synchronization.
+func (accessInfo *AccessInfo) ClockOffset() (time.Duration, time.Duration) {
+	clock := accessInfo.serverClock()
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.offset, clock.rtt
+}
+
+// serverClock gets the clock of an AccessInfo, creating it if the AccessInfo was not created by a constructor.
+func (accessInfo *AccessInfo) serverClock() *serverClock {
+	clocksMu.Lock()
+	defer clocksMu.Unlock()
+	if accessInfo.clock == nil {
+		accessInfo.clock = &serverClock{}
+	}
+	return accessInfo.clock
+}
+
+// now gets the current time of the exchange, as estimated by the last clock synchronization.
+func (accessInfo *AccessInfo) now() time.Time {
+	offset, _ := accessInfo.ClockOffset()
+	return time.Now().Add(offset)
+}
+
+// refreshClock synchronizes the clock if clock synchronization is enabled and the last synchronization is too old.
+// If the synchronization fails, the previous offset is kept and the failure is logged.
+func (accessInfo *AccessInfo) refreshClock(ctx context.Context) {
+	if ctx.Value(skipClockSync{}) != nil {
+		return
+	}
+	clock := accessInfo.serverClock()
+	// the interval is only set by the constructor, so it can be read without the lock.
+	if clock.interval <= 0 {
+		return
+	}
+	// only one request synchronizes the clock at a time; the others use the previous offset.
+	clock.mu.Lock()
+	stale := !clock.syncing && time.Since(clock.syncedAt) >= clock.interval
+	clock.syncing = clock.syncing || stale
+	clock.mu.Unlock()
+	if !stale {
+		return
+	}
+	if _, _, err := accessInfo.SyncClock(ctx); err != nil {
+		accessInfo.logger().Log(ctx, LevelWarn, "clock synchronization failed", "error", err)
+		clock.mu.Lock()
+		// retry after half an interval rather than at every request.
+		clock.syncedAt = time.Now().Add(-clock.interval / 2)
+		clock.mu.Unlock()
+	}
+	clock.mu.Lock()
+	clock.syncing = false
+	clock.mu.Unlock()
+}
@@ -0,0 +1,138 @@
+package gdax_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+// mockServerTime mocks GET /time with a clock that is ahead of the local clock by the specified skew.
+func mockServerTime(skew time.Duration) {
+	now := time.Now().Add(skew)
+	gock.New(gdax.EndPoint).
+		Get("/time").
+		Reply(http.StatusOK).
+		BodyString(fmt.Sprintf(`{"iso": "%s", "epoch": %.3f}`, now.UTC().Format(time.RFC3339Nano), float64(now.UnixNano())/1e9))
+}
+
+func TestGetTime(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/time").
+		Reply(http.StatusOK).
+		BodyString(`{"iso": "2015-01-07T23:47:25.201Z", "epoch": 1420674445.201}`)
+
+	serverTime, err := accessInfo.GetTime()
+	assert.NoError(err)
+	assert.Equal(serverTime.ISO.UnixNano()/1e6, int64(1420674445201))
+	assert.InDelta(serverTime.Time().UnixNano(), serverTime.ISO.UnixNano(), 1e3)
+}
+
+func TestSyncClock(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	mockServerTime(time.Hour)
+	offset, rtt, err := accessInfo.SyncClock(context.Background())
+	assert.NoError(err)
+	assert.InDelta(offset, time.Hour, float64(time.Second))
+	assert.GreaterOrEqual(rtt, time.Duration(0))
+	reported, _ := accessInfo.ClockOffset()
+	assert.Equal(reported, offset)
+}
+
+func TestRunClockSync(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	now := time.Now().Add(time.Hour)
+	gock.New(gdax.EndPoint).
+		Get("/time").
+		Times(2).
+		Reply(http.StatusOK).
+		BodyString(fmt.Sprintf(`{"iso": "%s", "epoch": %.3f}`, now.UTC().Format(time.RFC3339Nano), float64(now.UnixNano())/1e9))
+
+	// an AccessInfo that was not created by a constructor is synchronized and read concurrently.
+	accessInfo := &gdax.AccessInfo{Client: &http.Client{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- accessInfo.RunClockSync(ctx, 10*time.Millisecond)
+	}()
+	assert.Eventually(func() bool { return !gock.IsPending() }, 5*time.Second, time.Millisecond)
+	offset, _ := accessInfo.ClockOffset()
+	assert.InDelta(offset, time.Hour, float64(time.Minute))
+	cancel()
+	assert.Equal(<-done, context.Canceled)
+}
+
+func TestClockSyncTimestamps(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	var timestamps []string
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables(
+		gdax.WithClockSync(time.Hour),
+		gdax.WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+			return gdax.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				timestamps = append(timestamps, req.Header.Get("CB-ACCESS-TIMESTAMP"))
+				return next.RoundTrip(req)
+			})
+		}),
+	)
+	assert.NoError(err)
+
+	// the clock is only synchronized once per interval.
+	mockServerTime(-time.Hour)
+	for i := 0; i < 2; i++ {
+		gock.New(gdax.EndPoint).
+			Get("/fees").
+			Reply(http.StatusOK).
+			BodyString(feesJSON)
+		_, err = accessInfo.GetFees()
+		assert.NoError(err)
+	}
+	assert.True(gock.IsDone())
+	assert.Len(timestamps, 3)
+	for _, timestamp := range timestamps[1:] {
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		assert.NoError(err)
+		assert.InDelta(seconds, time.Now().Add(-time.Hour).Unix(), 5)
+	}
+}
+
+func TestAuthenticateSubscription(t *testing.T) {
+	assert := assert.New(t)
+
+	secret := []byte("secret")
+	accessInfo, err := gdax.NewAccessInfo("my-public-key", base64.StdEncoding.EncodeToString(secret), "my-passphrase")
+	assert.NoError(err)
+
+	subscription := feedSubscription
+	assert.NoError(accessInfo.AuthenticateSubscription(&subscription))
+	assert.Equal(subscription.Key, "my-public-key")
+	assert.Equal(subscription.Passphrase, "my-passphrase")
+	assert.Empty(feedSubscription.Signature)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(subscription.Timestamp + http.MethodGet + "/users/self/verify"))
+	assert.Equal(subscription.Signature, base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
@@ -1,6 +1,7 @@
 package gdax_test
 
 import (
+	"context"
 	"net/http"
 	"testing"
 
@@ -89,9 +90,7 @@ func TestGetCoinbaseAccountsError(t *testing.T) {
 		BodyString(`{"message": "Account id not found"}`)
 
 	coinbaseAccounts := accessInfo.GetCoinbaseAccounts()
-	assert.True(coinbaseAccounts.HasNext())
-
-	coinbaseAccount, err := coinbaseAccounts.Next()
+	coinbaseAccount, err := coinbaseAccounts.Next(context.Background())
 	assert.Error(err)
 	assert.Nil(coinbaseAccount)
 	assert.Equal(err.Error(), "Account id not found")
@@ -111,10 +110,10 @@ func TestGetCoinbaseAccounts(t *testing.T) {
 
 	var ids = [...]string{"fc3a8a57-7142-542d-8436-95a3d82e1622", "2ae3354e-f1c3-5771-8a37-6228e9d239db", "1bfad868-5223-5d3c-8a22-b5ed371e55cb", "2a11354e-f133-5771-8a37-622be9b239db"}
 
-	for idx, coinbaseAccounts := 0, accessInfo.GetCoinbaseAccounts(); coinbaseAccounts.HasNext(); idx++ {
-		coinbaseAccount, err := coinbaseAccounts.Next()
-		assert.NoError(err)
-
+	coinbaseAccounts, err := accessInfo.GetCoinbaseAccounts().Collect(context.Background())
+	assert.NoError(err)
+	assert.Len(coinbaseAccounts, len(ids))
+	for idx, coinbaseAccount := range coinbaseAccounts {
 		parsedID, err := uuid.Parse(ids[idx])
 		assert.NoError(err)
 
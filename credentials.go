@@ -0,0 +1,260 @@
+package gdax
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Credentials are the API key, secret, and passphrase used to sign requests.
+type Credentials struct {
+	PublicKey  string `json:"public_api"`
+	PrivateKey string `json:"private_api"`
+	Passphrase string `json:"passphrase"`
+}
+
+// A CredentialProvider retrieves the Credentials used to sign each request.
+// Retrieve is called for every request, so implementations that are slow to load should cache (see ReloadingCredentials).
+type CredentialProvider interface {
+	Retrieve(ctx context.Context) (Credentials, error)
+}
+
+// Validate checks that every credential is present and that the private key is base64-encoded.
+func (credentials Credentials) Validate() error {
+	return credentials.validate(true)
+}
+
+// validate checks the credentials. The private key is not needed if requests are signed by a custom Signer.
+func (credentials Credentials) validate(requirePrivateKey bool) error {
+	var missing []string
+	if credentials.PublicKey == "" {
+		missing = append(missing, "public key")
+	}
+	if credentials.PrivateKey == "" && requirePrivateKey {
+		missing = append(missing, "private key")
+	}
+	if credentials.Passphrase == "" {
+		missing = append(missing, "passphrase")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing credentials: %v", missing)
+	}
+	if _, err := base64.StdEncoding.DecodeString(credentials.PrivateKey); err != nil {
+		return errors.New("the private key is not base64-encoded")
+	}
+	return nil
+}
+
+// Retrieve gets the Credentials themselves, so that fixed Credentials can be used as a CredentialProvider.
+func (credentials Credentials) Retrieve(context.Context) (Credentials, error) {
+	return credentials, nil
+}
+
+// An envCredentials retrieves credentials from environment variables.
+type envCredentials struct {
+	prefix string
+}
+
+// EnvCredentials creates a CredentialProvider that reads the environment variables PUBLIC_KEY, PRIVATE_KEY, and
+// PASSPHRASE with the specified prefix (e.g., "GDAX_" reads GDAX_PUBLIC_KEY).
+// Unlike RetrieveAccessInfoFromEnvironmentVariables, missing or malformed credentials are an error, except for a missing
+// private key, which is only an error when a request is signed with it (i.e., without WithSigner).
+// The variables are read for every request, so changing them rotates the credentials.
+func EnvCredentials(prefix string) CredentialProvider {
+	return envCredentials{prefix: prefix}
+}
+
+// Retrieve reads and validates the credentials from the environment.
+func (provider envCredentials) Retrieve(context.Context) (Credentials, error) {
+	credentials := Credentials{
+		PublicKey:  os.Getenv(provider.prefix + "PUBLIC_KEY"),
+		PrivateKey: os.Getenv(provider.prefix + "PRIVATE_KEY"),
+		Passphrase: os.Getenv(provider.prefix + "PASSPHRASE"),
+	}
+	if err := credentials.validate(false); err != nil {
+		return Credentials{}, fmt.Errorf("environment variables with prefix %q: %v", provider.prefix, err)
+	}
+	return credentials, nil
+}
+
+// A fileCredentials retrieves credentials from a JSON file.
+type fileCredentials struct {
+	path string
+}
+
+// FileCredentials creates a CredentialProvider that reads a JSON file in the format of RetrieveAccessInfoFromFile.
+// The file must not be accessible by other users (e.g., its mode must be 0600 or 0400). As with EnvCredentials, the
+// private key may be omitted if requests are signed with WithSigner.
+// The file is read for every request; wrap the provider with ReloadingCredentials to read it less often.
+func FileCredentials(path string) CredentialProvider {
+	return fileCredentials{path: path}
+}
+
+// Retrieve reads and validates the credentials from the file.
+func (provider fileCredentials) Retrieve(context.Context) (Credentials, error) {
+	info, err := os.Stat(provider.path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	// Windows does not have Unix permission bits.
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return Credentials{}, fmt.Errorf("%s is accessible by other users (mode %v); restrict it to its owner", provider.path, info.Mode().Perm())
+	}
+	data, err := os.ReadFile(provider.path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	var credentials Credentials
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return Credentials{}, err
+	}
+	if err := credentials.validate(false); err != nil {
+		return Credentials{}, fmt.Errorf("%s: %v", provider.path, err)
+	}
+	return credentials, nil
+}
+
+// A ReloadingCredentials caches the credentials of another provider and reloads them periodically, so that rotated
+// keys are picked up without restarting. If a reload fails, the last credentials that loaded are kept.
+type ReloadingCredentials struct {
+	provider CredentialProvider
+	interval time.Duration
+
+	mu          sync.Mutex
+	credentials Credentials
+	loaded      bool
+	loadedAt    time.Time
+	lastErr     error
+	// refreshing is closed when the reload in progress finishes, or is nil if there is none.
+	refreshing chan struct{}
+}
+
+// NewReloadingCredentials creates a ReloadingCredentials that reloads the specified provider at most once per interval.
+func NewReloadingCredentials(provider CredentialProvider, interval time.Duration) *ReloadingCredentials {
+	return &ReloadingCredentials{provider: provider, interval: interval}
+}
+
+// Retrieve gets the cached credentials, reloading them first if they are older than the interval.
+// Only one caller reloads at a time; while it does, the others get the cached credentials, or wait for the reload if
+// the credentials have never loaded. An error is only returned if the credentials have never loaded.
+func (reloading *ReloadingCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	reloading.mu.Lock()
+	for {
+		attempted := !reloading.loadedAt.IsZero() && time.Since(reloading.loadedAt) < reloading.interval
+		if attempted || (reloading.loaded && reloading.refreshing != nil) {
+			defer reloading.mu.Unlock()
+			if !reloading.loaded {
+				return Credentials{}, reloading.lastErr
+			}
+			return reloading.credentials, nil
+		}
+		if reloading.refreshing == nil {
+			break
+		}
+		done := reloading.refreshing
+		reloading.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return Credentials{}, ctx.Err()
+		}
+		reloading.mu.Lock()
+	}
+	reloading.mu.Unlock()
+
+	reloading.reload(ctx)
+	reloading.mu.Lock()
+	defer reloading.mu.Unlock()
+	if !reloading.loaded {
+		return Credentials{}, reloading.lastErr
+	}
+	return reloading.credentials, nil
+}
+
+// Reload reloads the credentials immediately (e.g., when a rotation is signalled).
+func (reloading *ReloadingCredentials) Reload(ctx context.Context) error {
+	return reloading.reload(ctx)
+}
+
+// Err gets the error of the last reload, if it failed.
+func (reloading *ReloadingCredentials) Err() error {
+	reloading.mu.Lock()
+	defer reloading.mu.Unlock()
+	return reloading.lastErr
+}
+
+// reload retrieves the credentials from the underlying provider. The lock is not held while the provider loads, so
+// requests are not blocked by a slow reload.
+func (reloading *ReloadingCredentials) reload(ctx context.Context) error {
+	reloading.mu.Lock()
+	done := make(chan struct{})
+	reloading.refreshing = done
+	reloading.mu.Unlock()
+
+	credentials, err := reloading.provider.Retrieve(ctx)
+	reloading.mu.Lock()
+	defer reloading.mu.Unlock()
+	defer close(done)
+	if reloading.refreshing == done {
+		reloading.refreshing = nil
+	}
+	// a failed reload is retried after the interval, not at every request.
+	reloading.loadedAt = time.Now()
+	reloading.lastErr = err
+	if err != nil {
+		return err
+	}
+	reloading.credentials = credentials
+	reloading.loaded = true
+	return nil
+}
+
+// WithCredentials signs every request with the credentials retrieved from the specified provider instead of the
+// PublicKey, PrivateKey, and Passphrase fields of the AccessInfo.
+func WithCredentials(provider CredentialProvider) ClientOption {
+	return func(config *clientConfig) error {
+		config.credentials = provider
+		return nil
+	}
+}
+
+// NewAccessInfoFromProvider creates an AccessInfo that signs every request with the credentials of the specified
+// provider. The credentials are retrieved once to check that they are valid; if a Signer is set with WithSigner,
+// the credentials do not need a private key.
+func NewAccessInfoFromProvider(ctx context.Context, provider CredentialProvider, options ...ClientOption) (*AccessInfo, error) {
+	accessInfo, err := NewAccessInfo("", "", "", append(slices.Clip(options), WithCredentials(provider))...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := accessInfo.retrieveCredentials(ctx); err != nil {
+		return nil, err
+	}
+	return accessInfo, nil
+}
+
+// retrieveCredentials gets the credentials that sign the next request.
+// The credentials of a provider are validated; the private key is only required if there is no custom Signer.
+func (accessInfo *AccessInfo) retrieveCredentials(ctx context.Context) (Credentials, error) {
+	if accessInfo.credentials == nil {
+		return Credentials{
+			PublicKey:  accessInfo.PublicKey,
+			PrivateKey: accessInfo.PrivateKey,
+			Passphrase: accessInfo.Passphrase,
+		}, nil
+	}
+	credentials, err := accessInfo.credentials.Retrieve(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if err := credentials.validate(accessInfo.signer == nil); err != nil {
+		return Credentials{}, err
+	}
+	return credentials, nil
+}
@@ -0,0 +1,264 @@
+package gdax_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+// writeCredentials writes a credentials file with the specified public key and mode.
+func writeCredentials(t *testing.T, path, publicKey string, mode os.FileMode) {
+	data := `{"public_api": "` + publicKey + `", "private_api": "c2VjcmV0", "passphrase": "my-passphrase"}`
+	assert.NoError(t, os.WriteFile(path, []byte(data), mode))
+	assert.NoError(t, os.Chmod(path, mode))
+}
+
+func TestEnvCredentials(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("GDAX_PUBLIC_KEY", "my-public-key")
+	t.Setenv("GDAX_PRIVATE_KEY", "c2VjcmV0")
+	t.Setenv("GDAX_PASSPHRASE", "my-passphrase")
+	credentials, err := gdax.EnvCredentials("GDAX_").Retrieve(context.Background())
+	assert.NoError(err)
+	assert.Equal(credentials, gdax.Credentials{PublicKey: "my-public-key", PrivateKey: "c2VjcmV0", Passphrase: "my-passphrase"})
+
+	t.Setenv("GDAX_PRIVATE_KEY", "not base64!")
+	_, err = gdax.EnvCredentials("GDAX_").Retrieve(context.Background())
+	assert.Error(err)
+
+	_, err = gdax.EnvCredentials("MISSING_").Retrieve(context.Background())
+	assert.Error(err)
+}
+
+func TestFileCredentials(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "credentials.json")
+
+	writeCredentials(t, path, "my-public-key", 0644)
+	_, err := gdax.FileCredentials(path).Retrieve(context.Background())
+	assert.Error(err)
+
+	writeCredentials(t, path, "my-public-key", 0600)
+	credentials, err := gdax.FileCredentials(path).Retrieve(context.Background())
+	assert.NoError(err)
+	assert.Equal(credentials.PublicKey, "my-public-key")
+}
+
+func TestReloadingCredentials(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	writeCredentials(t, path, "old-key", 0600)
+
+	var keys []string
+	provider := gdax.NewReloadingCredentials(gdax.FileCredentials(path), time.Hour)
+	accessInfo, err := gdax.NewAccessInfoFromProvider(context.Background(), provider,
+		gdax.WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+			return gdax.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				keys = append(keys, req.Header.Get("CB-ACCESS-KEY"))
+				return next.RoundTrip(req)
+			})
+		}))
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/fees").
+		Times(3).
+		Reply(http.StatusOK).
+		BodyString(feesJSON)
+
+	_, err = accessInfo.GetFees()
+	assert.NoError(err)
+	// the rotated key is not picked up until the credentials are reloaded.
+	writeCredentials(t, path, "new-key", 0600)
+	_, err = accessInfo.GetFees()
+	assert.NoError(err)
+	assert.NoError(provider.Reload(context.Background()))
+	_, err = accessInfo.GetFees()
+	assert.NoError(err)
+	assert.Equal(keys, []string{"old-key", "old-key", "new-key"})
+
+	// a broken rotation keeps the last good credentials.
+	writeCredentials(t, path, "", 0600)
+	assert.Error(provider.Reload(context.Background()))
+	credentials, err := provider.Retrieve(context.Background())
+	assert.NoError(err)
+	assert.Equal(credentials.PublicKey, "new-key")
+}
+
+// A slowProvider is a CredentialProvider that blocks every reload after the first until it is released.
+type slowProvider struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (provider *slowProvider) Retrieve(context.Context) (gdax.Credentials, error) {
+	call := provider.calls.Add(1)
+	if call > 1 {
+		<-provider.release
+	}
+	return gdax.Credentials{PublicKey: "key-" + strconv.Itoa(int(call)), PrivateKey: "c2VjcmV0", Passphrase: "my-passphrase"}, nil
+}
+
+func TestReloadingCredentialsSlowReload(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	slow := &slowProvider{release: make(chan struct{})}
+	provider := gdax.NewReloadingCredentials(slow, time.Nanosecond)
+	credentials, err := provider.Retrieve(ctx)
+	assert.NoError(err)
+	assert.Equal(credentials.PublicKey, "key-1")
+
+	reloaded := make(chan gdax.Credentials)
+	go func() {
+		credentials, _ := provider.Retrieve(ctx)
+		reloaded <- credentials
+	}()
+	for slow.calls.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// while one caller reloads, the others get the cached credentials without waiting or reloading again.
+	credentials, err = provider.Retrieve(ctx)
+	assert.NoError(err)
+	assert.Equal(credentials.PublicKey, "key-1")
+	close(slow.release)
+	assert.Equal((<-reloaded).PublicKey, "key-2")
+	assert.Equal(slow.calls.Load(), int32(2))
+}
+
+func TestNewAccessInfoFromProviderOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	// the options of the caller are not overwritten, even if the slice has spare capacity.
+	options := make([]gdax.ClientOption, 1, 2)
+	options[0] = gdax.WithTimeout(time.Second)
+	_, err := gdax.NewAccessInfoFromProvider(context.Background(), gdax.Credentials{PublicKey: "my-public-key", PrivateKey: "c2VjcmV0", Passphrase: "my-passphrase"}, options...)
+	assert.NoError(err)
+	assert.Nil(options[:2][1])
+}
+
+func TestNewAccessInfoFromProviderInvalid(t *testing.T) {
+	_, err := gdax.NewAccessInfoFromProvider(context.Background(), gdax.Credentials{PublicKey: "my-public-key"})
+	assert.Error(t, err)
+
+	_, err = gdax.NewAccessInfoFromProvider(context.Background(), gdax.EnvCredentials("MISSING_"))
+	assert.Error(t, err)
+}
+
+// A remoteSigner is a Signer that never sees the secret.
+type remoteSigner struct {
+	prehashes []string
+}
+
+func (signer *remoteSigner) Sign(timestamp, method, requestPath, body string) (string, error) {
+	signer.prehashes = append(signer.prehashes, timestamp+method+requestPath+body)
+	return "signed-remotely", nil
+}
+
+func TestSigner(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	signer := remoteSigner{}
+	accessInfo, err := gdax.NewAccessInfoFromProvider(context.Background(),
+		gdax.Credentials{PublicKey: "my-public-key", Passphrase: "my-passphrase"},
+		gdax.WithSigner(&signer))
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/fees").
+		MatchHeader("CB-ACCESS-SIGN", "signed-remotely").
+		MatchHeader("CB-ACCESS-KEY", "my-public-key").
+		Reply(http.StatusOK).
+		BodyString(feesJSON)
+
+	_, err = accessInfo.GetFees()
+	assert.NoError(err)
+
+	subscription := feedSubscription
+	assert.NoError(accessInfo.AuthenticateSubscription(&subscription))
+	assert.Equal(subscription.Signature, "signed-remotely")
+	assert.Len(signer.prehashes, 2)
+	assert.True(strings.HasSuffix(signer.prehashes[0], "GET/fees"))
+	assert.Equal(signer.prehashes[1], subscription.Timestamp+"GET/users/self/verify")
+}
+
+func TestSignerWithEnvCredentials(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	// the private key is kept by the signer, so it is not in the environment.
+	t.Setenv("REMOTE_PUBLIC_KEY", "my-public-key")
+	t.Setenv("REMOTE_PASSPHRASE", "my-passphrase")
+	signer := remoteSigner{}
+	accessInfo, err := gdax.NewAccessInfoFromProvider(context.Background(), gdax.EnvCredentials("REMOTE_"), gdax.WithSigner(&signer))
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/fees").
+		MatchHeader("CB-ACCESS-SIGN", "signed-remotely").
+		MatchHeader("CB-ACCESS-KEY", "my-public-key").
+		Reply(http.StatusOK).
+		BodyString(feesJSON)
+	_, err = accessInfo.GetFees()
+	assert.NoError(err)
+	assert.Len(signer.prehashes, 1)
+
+	// without a signer, the private key is required.
+	_, err = gdax.NewAccessInfoFromProvider(context.Background(), gdax.EnvCredentials("REMOTE_"))
+	assert.EqualError(err, "missing credentials: [private key]")
+}
+
+func TestRotatedPrivateKey(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	t.Setenv("ROTATED_PUBLIC_KEY", "my-public-key")
+	t.Setenv("ROTATED_PRIVATE_KEY", base64.StdEncoding.EncodeToString([]byte("old")))
+	t.Setenv("ROTATED_PASSPHRASE", "my-passphrase")
+	accessInfo, err := gdax.NewAccessInfoFromProvider(context.Background(), gdax.EnvCredentials("ROTATED_"))
+	assert.NoError(err)
+
+	var signatures []string
+	for _, secret := range []string{"old", "old", "new"} {
+		t.Setenv("ROTATED_PRIVATE_KEY", base64.StdEncoding.EncodeToString([]byte(secret)))
+		subscription := feedSubscription
+		assert.NoError(accessInfo.AuthenticateSubscription(&subscription))
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(subscription.Timestamp + http.MethodGet + "/users/self/verify"))
+		assert.Equal(subscription.Signature, base64.StdEncoding.EncodeToString(mac.Sum(nil)), secret)
+		signatures = append(signatures, subscription.Signature)
+	}
+	assert.NotEqual(signatures[1], signatures[2])
+}
+
+func TestHMACSigner(t *testing.T) {
+	assert := assert.New(t)
+
+	signer, err := gdax.NewHMACSigner(base64.StdEncoding.EncodeToString([]byte("secret")))
+	assert.NoError(err)
+	signature, err := signer.Sign("1420674445", http.MethodPost, "/orders", `{"size": "1"}`)
+	assert.NoError(err)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(`1420674445POST/orders{"size": "1"}`))
+	assert.Equal(signature, base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+
+	_, err = gdax.NewHMACSigner("not base64!")
+	assert.Error(err)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"io/ioutil"
 	"log"
@@ -62,25 +63,23 @@ func ParseFlags() {
 }
 
 func performLinearRegression() error {
-	err := gdax.Feed(&gdax.Subscription{
+	handlers := gdax.NewHandlers().
+		OnError(func(e gdax.Error) {
+			log.Printf("error: %+v\n", e)
+		}).
+		OnMatch(func(match gdax.Match) {
+			linearRegression.AddPoint(float64(match.Time.Unix()), match.Price)
+			a, b := linearRegression.GetCoefficients()
+			log.Println(a, b, match.Price)
+		}).
+		OnUnknown(func(unknown gdax.Unknown) {
+			log.Printf("unknown: %s\n", unknown.Raw)
+		})
+	return gdax.Feed(&gdax.Subscription{
 		Type:       gdax.SubscribeType,
 		Channels:   []string{gdax.MatchesType},
 		ProductIDs: []string{"BTC-USD"},
-	},
-		func(message gdax.Message) {
-			switch message.MessageType() {
-			case gdax.ErrorType:
-				log.Printf("error: %+v\n", message.(gdax.Error))
-			case gdax.MatchType:
-				match := message.(gdax.Match)
-				linearRegression.AddPoint(float64(match.Time.Unix()), match.Price)
-				a, b := linearRegression.GetCoefficients()
-				log.Println(a, b, match.Price)
-			default:
-				log.Printf("unknown: %+v\n", message)
-			}
-		})
-	return err
+	}, handlers.Handle)
 }
 
 func main() {
@@ -98,8 +97,11 @@ func main() {
 	if err != nil {
 		log.Panic(err)
 	}
-	for accounts := accessInfo.GetAccountHistory(&accountID); accounts.HasNext(); {
-		history, err := accounts.Next()
+	for accounts := accessInfo.GetAccountHistory(&accountID); ; {
+		history, err := accounts.Next(context.Background())
+		if err == gdax.ErrDone {
+			break
+		}
 		if err != nil {
 			log.Panic(err)
 		}
@@ -123,8 +125,11 @@ func main() {
 	// log.Printf("%+v\n", orderResponse)
 
 	// time.Sleep(0 * time.Second)
-	// for orders := accessInfo.GetOrdersForProduct("BTC-USD"); orders.HasNext(); {
-	//   order, err := orders.Next()
+	// for orders := accessInfo.GetOrdersForProduct("BTC-USD"); ; {
+	//   order, err := orders.Next(context.Background())
+	//   if err == gdax.ErrDone {
+	//     break
+	//   }
 	//   if err != nil {
 	//     log.Panic(err)
 	//   }
@@ -136,24 +141,33 @@ func main() {
 	//   log.Println("order confirmation", o)
 	// }
 
-	// for fills := accessInfo.GetFills(); fills.HasNext(); {
-	//   fill, err := fills.Next()
+	// for fills := accessInfo.GetFills(); ; {
+	//   fill, err := fills.Next(context.Background())
+	//   if err == gdax.ErrDone {
+	//     break
+	//   }
 	//   if err != nil {
 	//     log.Panic(err)
 	//   }
 	//   log.Println("fill", fill)
 	// }
 
-	// for cas := accessInfo.GetCoinbaseAccounts(); cas.HasNext(); {
-	//   ca, err := cas.Next()
+	// for cas := accessInfo.GetCoinbaseAccounts(); ; {
+	//   ca, err := cas.Next(context.Background())
+	//   if err == gdax.ErrDone {
+	//     break
+	//   }
 	//   if err != nil {
 	//     log.Panic(err)
 	//   }
 	//   log.Println("coinbase account", ca)
 	// }
 
-	// for accounts := accessInfo.GetAccounts(); accounts.HasNext(); {
-	//   account, err := accounts.Next()
+	// for accounts := accessInfo.GetAccounts(); ; {
+	//   account, err := accounts.Next(context.Background())
+	//   if err == gdax.ErrDone {
+	//     break
+	//   }
 	//   if err != nil {
 	//     log.Panic(err)
 	//   }
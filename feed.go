@@ -1,8 +1,11 @@
 package gdax
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strconv"
 	"time"
 
@@ -23,9 +26,11 @@ const (
 	FullType          = "full"
 	ErrorType         = "error"
 	SubscribeType     = "subscribe"
-	addr              = "wss://ws-feed.gdax.com"
 )
 
+// FeedEndPoint is the GDAX websocket feed endpoint.
+const FeedEndPoint = "wss://ws-feed.gdax.com"
+
 // A Message allows for the retrieval of the type of the message.
 type Message interface {
 	MessageType() string
@@ -34,7 +39,7 @@ type Message interface {
 // A message stores information about a single message sent in a channel.
 type message struct {
 	Type      string `json:"type"`
-	ProductID string `json:"product_id,,omitempty"`
+	ProductID string `json:"product_id,omitempty"`
 }
 
 // A Bid stores a single bid from a snapshot.
@@ -68,6 +73,12 @@ type Subscription struct {
 	Type       string   `json:"type"`
 	Channels   []string `json:"channels"`
 	ProductIDs []string `json:"product_ids"`
+
+	// Signature, Key, Passphrase, and Timestamp are set by AccessInfo.AuthenticateSubscription.
+	Signature  string `json:"signature,omitempty"`
+	Key        string `json:"key,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+	Timestamp  string `json:"timestamp,omitempty"`
 }
 
 // A Heartbeat is channel message sent after subscribing to the heartbeat channel.
@@ -81,23 +92,21 @@ type Heartbeat struct {
 // A Ticker is channel message sent after subscribing to the ticker channel.
 type Ticker struct {
 	message
-	TradeID   int64      `json:"trade_id"`
-	Sequence  int64      `json:"sequence"`
-	Time      *time.Time `json:"time,string"`
-	ProductID string     `json:"product_id"`
-	Price     float64    `json:"price,string"`
-	Side      string     `json:"side"`
-	LastSize  float64    `json:"last_size,string"`
-	BestBid   float64    `json:"best_bid,string"`
-	BestAsk   float64    `json:"best_ask,string"`
+	TradeID  int64      `json:"trade_id"`
+	Sequence int64      `json:"sequence"`
+	Time     *time.Time `json:"time,string"`
+	Price    float64    `json:"price,string"`
+	Side     string     `json:"side"`
+	LastSize float64    `json:"last_size,string"`
+	BestBid  float64    `json:"best_bid,string"`
+	BestAsk  float64    `json:"best_ask,string"`
 }
 
 // A Snapshot is channel message sent after subscribing to the snapshot channel.
 type Snapshot struct {
 	message
-	ProductID string `json:"product_id"`
-	Bids      []Bid  `json:"bids"`
-	Asks      []Ask  `json:"asks"`
+	Bids []Bid `json:"bids"`
+	Asks []Ask `json:"asks"`
 }
 
 // A L2Update is channel message sent after subscribing to the L2Update channel.
@@ -119,6 +128,13 @@ type Match struct {
 	Side         string     `json:"side"`
 }
 
+// An Unknown is a channel message of a type that this package does not model (e.g., subscriptions).
+type Unknown struct {
+	message
+	Sequence int64           `json:"sequence"`
+	Raw      json.RawMessage `json:"-"`
+}
+
 // Error returns the message of an Error.
 func (err Error) Error() string {
 	return err.Message
@@ -129,135 +145,206 @@ func (m message) MessageType() string {
 	return m.Type
 }
 
+// productID returns the ProductID of a message.
+func (m message) productID() string {
+	return m.ProductID
+}
+
 // UnmarshalJSON converts a JSON bytes stream into an L2Update.
 func (m *L2Update) UnmarshalJSON(b []byte) error {
-	var fields map[string]interface{}
+	var fields struct {
+		message
+		Changes [][]string `json:"changes"`
+	}
 	if err := json.Unmarshal(b, &fields); err != nil {
 		return err
 	}
-	for key, val := range fields {
-		switch key {
-		case "type":
-			m.Type = val.(string)
-		case "product_id":
-			m.ProductID = val.(string)
-		case "changes":
-			for _, e := range val.([]interface{}) {
-				side := e.([]interface{})[0].(string)
-				price, err := strconv.ParseFloat(e.([]interface{})[1].(string), 64)
-				if err != nil {
-					return err
-				}
-				size, err := strconv.ParseFloat(e.([]interface{})[2].(string), 64)
-				if err != nil {
-					return err
-				}
-				m.Changes = append(m.Changes, Change{Side: side, Price: price, Size: size})
-			}
+	m.message = fields.message
+	m.Changes = make([]Change, 0, len(fields.Changes))
+	for _, e := range fields.Changes {
+		if len(e) < 3 {
+			return fmt.Errorf("malformed l2update change: %v", e)
 		}
+		price, err := strconv.ParseFloat(e[1], 64)
+		if err != nil {
+			return err
+		}
+		size, err := strconv.ParseFloat(e[2], 64)
+		if err != nil {
+			return err
+		}
+		m.Changes = append(m.Changes, Change{Side: e[0], Price: price, Size: size})
 	}
 	return nil
 }
 
 // UnmarshalJSON converts a JSON bytes stream into an Snapshot.
 func (m *Snapshot) UnmarshalJSON(b []byte) error {
-	var fields map[string]interface{}
+	var fields struct {
+		message
+		Bids [][]string `json:"bids"`
+		Asks [][]string `json:"asks"`
+	}
 	if err := json.Unmarshal(b, &fields); err != nil {
 		return err
 	}
-	for key, val := range fields {
-		switch key {
-		case "type":
-			m.Type = val.(string)
-		case "product_id":
-			m.ProductID = val.(string)
-		case "bids":
-			for _, e := range val.([]interface{}) {
-				price, err := strconv.ParseFloat(e.([]interface{})[0].(string), 64)
-				if err != nil {
-					return err
-				}
-				size, err := strconv.ParseFloat(e.([]interface{})[1].(string), 64)
-				if err != nil {
-					return err
-				}
-				m.Bids = append(m.Bids, Bid{Price: price, Size: size})
-			}
-		case "asks":
-			for _, e := range val.([]interface{}) {
-				price, err := strconv.ParseFloat(e.([]interface{})[0].(string), 64)
-				if err != nil {
-					return err
-				}
-				size, err := strconv.ParseFloat(e.([]interface{})[1].(string), 64)
-				if err != nil {
-					return err
-				}
-				m.Asks = append(m.Asks, Ask{Price: price, Size: size})
-			}
+	m.message = fields.message
+	m.Bids = make([]Bid, 0, len(fields.Bids))
+	for _, e := range fields.Bids {
+		price, size, err := parseLevel(e)
+		if err != nil {
+			return err
 		}
+		m.Bids = append(m.Bids, Bid{Price: price, Size: size})
+	}
+	m.Asks = make([]Ask, 0, len(fields.Asks))
+	for _, e := range fields.Asks {
+		price, size, err := parseLevel(e)
+		if err != nil {
+			return err
+		}
+		m.Asks = append(m.Asks, Ask{Price: price, Size: size})
 	}
 	return nil
 }
 
+// parseLevel parses the price and size of a single snapshot level.
+func parseLevel(level []string) (float64, float64, error) {
+	if len(level) < 2 {
+		return 0, 0, fmt.Errorf("malformed snapshot level: %v", level)
+	}
+	price, err := strconv.ParseFloat(level[0], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, err := strconv.ParseFloat(level[1], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return price, size, nil
+}
+
 // Feed makes a subscription to the specified channel and sends any incoming messages to the specified message handler.
 // Note that this function is blocking; this function only terminates if the connection is dropped/terminated or an error is sent.
+// The message handler runs on the read loop, so a slow handler stalls the socket; use Stream to decouple them.
 func Feed(s *Subscription, messageHandler func(Message)) error {
-	body, err := json.Marshal(*s)
+	return RecordFeed(s, nil, messageHandler)
+}
+
+// RecordFeed is like Feed, but also writes every raw frame to the specified recorder before it is handled.
+// The recording can be replayed into the same message handler with Replay.
+func RecordFeed(s *Subscription, recorder *Recorder, messageHandler func(Message)) error {
+	conn, err := dialFeed(context.Background(), FeedEndPoint, s)
 	if err != nil {
 		return err
 	}
-	messageType := make(chan string)
-	jsonString := make(chan []byte)
-	errorChan := make(chan error)
-	if err = createWebsocketConnection(addr, body, messageType, jsonString, errorChan); err != nil {
-		return err
-	}
+	defer conn.Close()
 	for {
-		if err := <-errorChan; err != nil {
+		frame, err := conn.readFrame()
+		if err != nil {
 			return err
 		}
-		messageTypeInstance := <-messageType
-		jsonInstance := <-jsonString
-		switch messageTypeInstance {
-		case HeartbeatType:
-			var heartbeat Heartbeat
-			if err := json.Unmarshal(jsonInstance, &heartbeat); err != nil {
-				return err
-			}
-			messageHandler(heartbeat)
-		case TickerType:
-			var ticker Ticker
-			if err := json.Unmarshal(jsonInstance, &ticker); err != nil {
+		if recorder != nil {
+			if err := recorder.Record(frame, time.Now()); err != nil {
 				return err
 			}
-			messageHandler(ticker)
-		case L2UpdateType:
-			var l2update L2Update
-			if err := json.Unmarshal(jsonInstance, &l2update); err != nil {
-				return err
-			}
-			messageHandler(l2update)
-		case SnapshotType:
-			var snapshot Snapshot
-			if err := json.Unmarshal(jsonInstance, &snapshot); err != nil {
-				return err
-			}
-			messageHandler(snapshot)
-		case MatchType:
-			var match Match
-			if err := json.Unmarshal(jsonInstance, &match); err != nil {
-				return err
-			}
-			messageHandler(match)
-		case ErrorType:
-			var e Error
-			if err := json.Unmarshal(jsonInstance, &e); err != nil {
-				return err
-			}
-			messageHandler(e)
+		}
+		message, err := decodeFrame(frame)
+		if err != nil {
+			return err
+		}
+		messageHandler(message)
+		if e, ok := message.(Error); ok {
 			return errors.New(e.Message)
 		}
 	}
-	return nil
+}
+
+// decodeFrame converts a single websocket frame into a Message, decoding it once.
+func decodeFrame(frame []byte) (Message, error) {
+	return decodeMessage(messageTypeOf(frame), frame)
+}
+
+// messageTypeOf finds the value of the top-level "type" key of a JSON message without decoding it.
+// The feed always sends "type" as the first key, so this is a short scan.
+// If there is no "type" key, the empty string is returned.
+func messageTypeOf(frame []byte) string {
+	key := []byte(`"type"`)
+	for offset := 0; ; {
+		idx := bytes.Index(frame[offset:], key)
+		if idx < 0 {
+			return ""
+		}
+		i := skipSpace(frame, offset+idx+len(key))
+		offset += idx + len(key)
+		if i >= len(frame) || frame[i] != ':' {
+			continue
+		}
+		i = skipSpace(frame, i+1)
+		if i >= len(frame) || frame[i] != '"' {
+			return ""
+		}
+		end := bytes.IndexByte(frame[i+1:], '"')
+		if end < 0 {
+			return ""
+		}
+		return string(frame[i+1 : i+1+end])
+	}
+}
+
+// skipSpace gets the index of the first non-whitespace byte at or after the specified index.
+func skipSpace(b []byte, i int) int {
+	for i < len(b) && (b[i] == ' ' || b[i] == '\t' || b[i] == '\n' || b[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// decodeMessage converts a JSON message of the specified type into a Message.
+// If the type of message is not supported, an Unknown is returned.
+func decodeMessage(messageType string, jsonMessage []byte) (Message, error) {
+	switch messageType {
+	case HeartbeatType:
+		var heartbeat Heartbeat
+		if err := json.Unmarshal(jsonMessage, &heartbeat); err != nil {
+			return nil, err
+		}
+		return heartbeat, nil
+	case TickerType:
+		var ticker Ticker
+		if err := json.Unmarshal(jsonMessage, &ticker); err != nil {
+			return nil, err
+		}
+		return ticker, nil
+	case L2UpdateType:
+		var l2update L2Update
+		if err := json.Unmarshal(jsonMessage, &l2update); err != nil {
+			return nil, err
+		}
+		return l2update, nil
+	case SnapshotType:
+		var snapshot Snapshot
+		if err := json.Unmarshal(jsonMessage, &snapshot); err != nil {
+			return nil, err
+		}
+		return snapshot, nil
+	case MatchType:
+		var match Match
+		if err := json.Unmarshal(jsonMessage, &match); err != nil {
+			return nil, err
+		}
+		return match, nil
+	case ErrorType:
+		var e Error
+		if err := json.Unmarshal(jsonMessage, &e); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+	var unknown Unknown
+	if err := json.Unmarshal(jsonMessage, &unknown); err != nil {
+		return nil, err
+	}
+	unknown.Raw = jsonMessage
+	return unknown, nil
 }
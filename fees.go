@@ -0,0 +1,109 @@
+package gdax
+
+import (
+	"net/http"
+	"time"
+)
+
+// Fees represents the current maker and taker fee rates of the user.
+type Fees struct {
+	MakerFeeRate float64 `json:"maker_fee_rate,string"`
+	TakerFeeRate float64 `json:"taker_fee_rate,string"`
+	USDVolume    float64 `json:"usd_volume,string"`
+}
+
+// A TrailingVolume represents the user's 30-day trailing volume for a single product.
+type TrailingVolume struct {
+	ProductID      string     `json:"product_id"`
+	ExchangeVolume float64    `json:"exchange_volume,string"`
+	Volume         float64    `json:"volume,string"`
+	RecordedAt     *time.Time `json:"recorded_at,string"`
+}
+
+// A FeeTier represents a single tier of a fee schedule.
+// A tier applies once the 30-day trailing USD volume reaches MinVolume.
+type FeeTier struct {
+	MinVolume    float64
+	MakerFeeRate float64
+	TakerFeeRate float64
+}
+
+// A FeeSchedule is a list of FeeTiers sorted by ascending MinVolume.
+type FeeSchedule []FeeTier
+
+// DefaultFeeSchedule is the published fee schedule of the exchange.
+// The rates returned by GetFees always take precedence; this schedule is only used to find the next tier.
+var DefaultFeeSchedule = FeeSchedule{
+	{MinVolume: 0, MakerFeeRate: 0.005, TakerFeeRate: 0.005},
+	{MinVolume: 10000, MakerFeeRate: 0.0035, TakerFeeRate: 0.0035},
+	{MinVolume: 50000, MakerFeeRate: 0.0015, TakerFeeRate: 0.0025},
+	{MinVolume: 100000, MakerFeeRate: 0.001, TakerFeeRate: 0.002},
+	{MinVolume: 1000000, MakerFeeRate: 0.0008, TakerFeeRate: 0.0018},
+	{MinVolume: 10000000, MakerFeeRate: 0.0005, TakerFeeRate: 0.0015},
+	{MinVolume: 50000000, MakerFeeRate: 0, TakerFeeRate: 0.001},
+	{MinVolume: 100000000, MakerFeeRate: 0, TakerFeeRate: 0.0007},
+	{MinVolume: 300000000, MakerFeeRate: 0, TakerFeeRate: 0.0006},
+	{MinVolume: 500000000, MakerFeeRate: 0, TakerFeeRate: 0.0005},
+	{MinVolume: 1000000000, MakerFeeRate: 0, TakerFeeRate: 0.0004},
+}
+
+// A TrailingVolumeCollection is an iterator of TrailingVolumes.
+type TrailingVolumeCollection = Iterator[*TrailingVolume]
+
+// GetFees gets the current maker and taker fee rates as well as the 30-day trailing USD volume.
+func (accessInfo *AccessInfo) GetFees() (*Fees, error) {
+	// GET /fees
+	var fees Fees
+	_, err := accessInfo.request(http.MethodGet, "/fees", "", &fees)
+	if err != nil {
+		return nil, err
+	}
+	return &fees, nil
+}
+
+// GetTrailingVolume gets the 30-day trailing volume for all products.
+func (accessInfo *AccessInfo) GetTrailingVolume() *TrailingVolumeCollection {
+	// GET /users/self/trailing-volume
+	return newIterator[*TrailingVolume](accessInfo, http.MethodGet, "/users/self/trailing-volume", "", "", false)
+}
+
+// EstimateFee estimates the fee, in quote currency, that would be charged for the specified order.
+// Post-only limit orders are charged the maker rate; all other orders are conservatively charged the taker rate.
+// The specified price is used when the order does not have a Price (e.g., market orders).
+func (f *Fees) EstimateFee(order *Order, price float64) float64 {
+	rate := f.TakerFeeRate
+	if order.Type == Limit && order.PostOnly {
+		rate = f.MakerFeeRate
+	}
+	if order.Type == Market && order.Funds != 0 {
+		return order.Funds * rate
+	}
+	if order.Price != 0 {
+		price = order.Price
+	}
+	return order.Size * price * rate
+}
+
+// Tier gets the FeeTier that applies to the specified 30-day trailing USD volume.
+func (s FeeSchedule) Tier(usdVolume float64) FeeTier {
+	var tier FeeTier
+	for _, t := range s {
+		if usdVolume < t.MinVolume {
+			break
+		}
+		tier = t
+	}
+	return tier
+}
+
+// NextTier gets the FeeTier after the one that applies to the specified 30-day trailing USD volume,
+// along with the additional USD volume needed to reach it.
+// The returned boolean is false if the volume is already in the last tier.
+func (s FeeSchedule) NextTier(usdVolume float64) (FeeTier, float64, bool) {
+	for _, t := range s {
+		if usdVolume < t.MinVolume {
+			return t, t.MinVolume - usdVolume, true
+		}
+	}
+	return FeeTier{}, 0, false
+}
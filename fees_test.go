@@ -0,0 +1,131 @@
+package gdax_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+const (
+	feesJSON = `
+		{
+		    "maker_fee_rate": "0.0015",
+		    "taker_fee_rate": "0.0025",
+		    "usd_volume": "25000.00"
+		}
+	`
+	trailingVolumeJSON = `
+		[
+		    {
+		        "product_id": "BTC-USD",
+		        "exchange_volume": "11800.00",
+		        "volume": "100.00",
+		        "recorded_at": "1973-11-29T00:05:01.123456Z"
+		    },
+		    {
+		        "product_id": "LTC-USD",
+		        "exchange_volume": "51010.04",
+		        "volume": "2010.04",
+		        "recorded_at": "1973-11-29T00:05:02.123456Z"
+		    }
+		]
+	`
+)
+
+func TestGetFeesError(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/fees").
+		Reply(http.StatusUnauthorized).
+		BodyString(`{"message": "Invalid API Key"}`)
+
+	fees, err := accessInfo.GetFees()
+	assert.Error(err)
+	assert.Nil(fees)
+	assert.Equal(err.Error(), "Invalid API Key")
+}
+
+func TestGetFees(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/fees").
+		Reply(http.StatusOK).
+		BodyString(feesJSON)
+
+	fees, err := accessInfo.GetFees()
+	assert.NoError(err)
+	assert.Equal(fees.MakerFeeRate, 0.0015)
+	assert.Equal(fees.TakerFeeRate, 0.0025)
+	assert.Equal(fees.USDVolume, 25000.0)
+}
+
+func TestGetTrailingVolume(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/users/self/trailing-volume").
+		Reply(http.StatusOK).
+		BodyString(trailingVolumeJSON)
+
+	var productIDs = [...]string{"BTC-USD", "LTC-USD"}
+	var volumes = [...]float64{100.00, 2010.04}
+
+	trailingVolumes, err := accessInfo.GetTrailingVolume().Collect(context.Background())
+	assert.NoError(err)
+	assert.Len(trailingVolumes, len(productIDs))
+	for idx, trailingVolume := range trailingVolumes {
+		assert.Equal(trailingVolume.ProductID, productIDs[idx])
+		assert.Equal(trailingVolume.Volume, volumes[idx])
+	}
+}
+
+func TestEstimateFee(t *testing.T) {
+	assert := assert.New(t)
+
+	fees := gdax.Fees{MakerFeeRate: 0.001, TakerFeeRate: 0.002}
+
+	postOnly := gdax.Order{Type: gdax.Limit, PostOnly: true, Price: 100, Size: 2}
+	assert.InDelta(fees.EstimateFee(&postOnly, 0), 0.2, 1e-9)
+
+	limit := gdax.Order{Type: gdax.Limit, Price: 100, Size: 2}
+	assert.InDelta(fees.EstimateFee(&limit, 0), 0.4, 1e-9)
+
+	marketSize := gdax.Order{Type: gdax.Market, Size: 2}
+	assert.InDelta(fees.EstimateFee(&marketSize, 50), 0.2, 1e-9)
+
+	marketFunds := gdax.Order{Type: gdax.Market, Funds: 1000}
+	assert.InDelta(fees.EstimateFee(&marketFunds, 50), 2, 1e-9)
+}
+
+func TestFeeScheduleNextTier(t *testing.T) {
+	assert := assert.New(t)
+
+	tier := gdax.DefaultFeeSchedule.Tier(25000)
+	assert.Equal(tier.MinVolume, 10000.0)
+
+	next, remaining, ok := gdax.DefaultFeeSchedule.NextTier(25000)
+	assert.True(ok)
+	assert.Equal(next.MinVolume, 50000.0)
+	assert.Equal(remaining, 25000.0)
+
+	_, _, ok = gdax.DefaultFeeSchedule.NextTier(2e9)
+	assert.False(ok)
+}
@@ -30,11 +30,7 @@ type Fill struct {
 }
 
 // A FillCollection is an iterator of Fills.
-type FillCollection struct {
-	pageableCollection
-	orderIDs  []*uuid.UUID
-	productID string
-}
+type FillCollection = Iterator[*Fill]
 
 // GetFills gets all fills with the specified orderIDs.
 func (accessInfo *AccessInfo) GetFills(orderIDs ...*uuid.UUID) *FillCollection {
@@ -43,43 +39,31 @@ func (accessInfo *AccessInfo) GetFills(orderIDs ...*uuid.UUID) *FillCollection {
 
 // GetFillsForProduct gets all fills for a specified productID and specified orderIDs.
 func (accessInfo *AccessInfo) GetFillsForProduct(productID string, orderIDs ...*uuid.UUID) *FillCollection {
-	fillCollection := FillCollection{
-		pageableCollection: accessInfo.newPageableCollection(true),
-		orderIDs:           orderIDs,
-		productID:          productID,
-	}
-	return &fillCollection
-}
-
-// HasNext determines if there is another Fill in this iterator.
-func (c *FillCollection) HasNext() bool {
 	// GET /fills
 	var (
 		orderParam   string
 		productParam string
-		fills        []Fill
 	)
 
-	if c.orderIDs != nil {
-		unparsedOrderIDs := make([]string, len(c.orderIDs))
-		for idx, orderID := range c.orderIDs {
+	if orderIDs != nil {
+		unparsedOrderIDs := make([]string, len(orderIDs))
+		for idx, orderID := range orderIDs {
 			unparsedOrderIDs[idx] = orderID.String()
 		}
 		orderParam = fmt.Sprintf("order_id=%s", strings.Join(unparsedOrderIDs, ","))
 	}
-	if c.productID != "" {
-		productParam = fmt.Sprintf("product_id=%s", c.productID)
+	if productID != "" {
+		productParam = fmt.Sprintf("product_id=%s", productID)
 	}
 
 	params := strings.Join(stringFilter([]string{orderParam, productParam}, notEmpty), "&")
-	return c.pageableCollection.hasNext(http.MethodGet, "/fills", params, "", &fills)
+	return newIterator[*Fill](accessInfo, http.MethodGet, "/fills", params, "", true)
 }
 
-// Next gets the next Fill from the iterator.
-func (c *FillCollection) Next() (*Fill, error) {
-	fill, err := c.pageableCollection.next()
-	if err != nil {
-		return nil, err
+// timestamp gets the creation time of a Fill.
+func (fill *Fill) timestamp() time.Time {
+	if fill.CreatedAt == nil {
+		return time.Time{}
 	}
-	return fill.Addr().Interface().(*Fill), nil
+	return *fill.CreatedAt
 }
@@ -1,10 +1,12 @@
 package gdax_test
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/ljeabmreosn/gdax"
@@ -45,8 +47,32 @@ const (
 		    }
 		]
 	`
+	fillJSONOld = `
+		[
+		    {
+		        "trade_id": 73,
+		        "product_id": "BTC-USD",
+		        "price": "8.00",
+		        "size": "0.03",
+		        "order_id": "b8d2a7b5-3bb1-4ef7-9d0c-7b0b0a0e0e5c",
+		        "created_at": "2014-11-06T22:19:28.578544Z",
+		        "liquidity": "M",
+		        "fee": "0.00000",
+		        "settled": true,
+		        "side": "sell"
+		    }
+		]
+	`
 )
 
+// fillsJSONSpanning is a page with a fill on Nov 7 (trade 74) and a fill on Nov 6 (trade 73), newest first.
+const fillsJSONSpanning = `
+	[
+	    {"trade_id": 74, "product_id": "BTC-USD", "price": "10.00", "size": "0.01", "created_at": "2014-11-07T22:19:28.578544Z", "liquidity": "T", "fee": "0.00025", "settled": true, "side": "buy"},
+	    {"trade_id": 73, "product_id": "BTC-USD", "price": "8.00", "size": "0.03", "created_at": "2014-11-06T22:19:28.578544Z", "liquidity": "M", "fee": "0.00000", "settled": true, "side": "sell"}
+	]
+`
+
 func TestGetFillsError(t *testing.T) {
 	defer gock.Off()
 	assert := assert.New(t)
@@ -65,9 +91,7 @@ func TestGetFillsError(t *testing.T) {
 	assert.NoError(err)
 
 	fills := accessInfo.GetFills(&parsedOrderID)
-	assert.True(fills.HasNext())
-
-	fill, err := fills.Next()
+	fill, err := fills.Next(context.Background())
 	assert.Error(err)
 	assert.Nil(fill)
 	assert.Equal(err.Error(), "Order id not found")
@@ -109,10 +133,10 @@ func TestGetFills(t *testing.T) {
 		parsedOrderIDs = append(parsedOrderIDs, &parsedOrderID)
 	}
 
-	for idx, fills := 0, accessInfo.GetFills(parsedOrderIDs[:]...); fills.HasNext(); idx++ {
-		fill, err := fills.Next()
-		assert.NoError(err)
-
+	fills, err := accessInfo.GetFills(parsedOrderIDs[:]...).Collect(context.Background())
+	assert.NoError(err)
+	assert.Len(fills, len(orderIDs))
+	for idx, fill := range fills {
 		assert.Equal(*fill.OrderID, *parsedOrderIDs[idx])
 	}
 }
@@ -153,11 +177,222 @@ func TestGetFillsForProduct(t *testing.T) {
 		parsedOrderIDs = append(parsedOrderIDs, &parsedOrderID)
 	}
 
-	for idx, fills := 0, accessInfo.GetFillsForProduct("BTC-USD", parsedOrderIDs[:]...); fills.HasNext(); idx++ {
-		fill, err := fills.Next()
-		assert.NoError(err)
-
+	fills, err := accessInfo.GetFillsForProduct("BTC-USD", parsedOrderIDs[:]...).Collect(context.Background())
+	assert.NoError(err)
+	assert.Len(fills, len(orderIDs))
+	for idx, fill := range fills {
 		assert.Equal(*fill.OrderID, *parsedOrderIDs[idx])
 		assert.Equal(fill.ProductID, "BTC-USD")
 	}
 }
+
+func TestGetFillsPaginate(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		MatchParam("product_id", "BTC-USD").
+		MatchParam("after", "5").
+		MatchParam("limit", "1").
+		Reply(http.StatusOK).
+		BodyString(fillJSON1).
+		SetHeader("CB-BEFORE", "4").
+		SetHeader("CB-AFTER", "4")
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		MatchParam("product_id", "BTC-USD").
+		MatchParam("after", "4").
+		MatchParam("limit", "1").
+		Reply(http.StatusOK).
+		BodyString(fillJSON2).
+		SetHeader("CB-BEFORE", "3").
+		SetHeader("CB-AFTER", "3")
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		MatchParam("product_id", "BTC-USD").
+		MatchParam("after", "3").
+		MatchParam("limit", "1").
+		Reply(http.StatusOK).
+		BodyString(fillJSONOld).
+		SetHeader("CB-BEFORE", "2").
+		SetHeader("CB-AFTER", "2")
+
+	fills := accessInfo.GetFillsForProduct("BTC-USD")
+	fills.Paginate(gdax.PageOptions{
+		Cursor:    "5",
+		Limit:     1,
+		StartTime: time.Date(2014, time.November, 7, 0, 0, 0, 0, time.UTC),
+	})
+
+	var orderIDs []string
+	for {
+		fill, err := fills.Next(context.Background())
+		if err == gdax.ErrDone {
+			break
+		}
+		assert.NoError(err)
+		orderIDs = append(orderIDs, fill.OrderID.String())
+	}
+	assert.Equal(orderIDs, []string{"d50ec984-77a8-460a-b958-66f114b0de9b", "03a7a57f-c5d5-4e29-b7a1-118b3a6cc88d"})
+	assert.Equal(fills.Before(), "4")
+	// the last page is older than StartTime, so resuming Older starts before the last yielded fill.
+	assert.Equal(fills.After(), "3")
+	assert.True(gock.IsDone())
+}
+
+func TestGetFillsPaginateNewer(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		MatchParam("before", "10").
+		Reply(http.StatusOK).
+		BodyString(fillJSON1).
+		SetHeader("CB-BEFORE", "11").
+		SetHeader("CB-AFTER", "11")
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		MatchParam("before", "11").
+		Reply(http.StatusOK).
+		BodyString("[]")
+
+	fills := accessInfo.GetFills().Paginate(gdax.PageOptions{Cursor: "10", Direction: gdax.Newer})
+
+	collected, err := fills.Collect(context.Background())
+	assert.NoError(err)
+	assert.Len(collected, 1)
+	assert.Equal(fills.Before(), "11")
+	assert.True(gock.IsDone())
+}
+
+// fillsPage creates a page of BTC-USD fills with the specified trade IDs.
+func fillsPage(tradeIDs ...int) string {
+	fills := make([]string, len(tradeIDs))
+	for idx, tradeID := range tradeIDs {
+		fills[idx] = `{"trade_id": ` + strconv.Itoa(tradeID) + `, "product_id": "BTC-USD", "price": "10.00", "size": "0.01", "side": "buy"}`
+	}
+	return "[" + strings.Join(fills, ",") + "]"
+}
+
+func TestGetFillsPaginateNewerOrder(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	for _, page := range []struct {
+		cursor   string
+		tradeIDs []int
+	}{
+		{"2", []int{4, 3}},
+		{"4", []int{6, 5}},
+		{"6", []int{7}},
+	} {
+		gock.New(gdax.EndPoint).
+			Get("/fills").
+			MatchParam("before", page.cursor).
+			MatchParam("limit", "2").
+			Reply(http.StatusOK).
+			BodyString(fillsPage(page.tradeIDs...)).
+			SetHeader("CB-BEFORE", strconv.Itoa(page.tradeIDs[0])).
+			SetHeader("CB-AFTER", strconv.Itoa(page.tradeIDs[len(page.tradeIDs)-1]))
+	}
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		MatchParam("before", "7").
+		Reply(http.StatusOK).
+		BodyString("[]")
+
+	fills := accessInfo.GetFills().Paginate(gdax.PageOptions{Cursor: "2", Direction: gdax.Newer, Limit: 2})
+	collected, err := fills.Collect(context.Background())
+	assert.NoError(err)
+	var tradeIDs []int64
+	for _, fill := range collected {
+		tradeIDs = append(tradeIDs, fill.TradeID)
+	}
+	assert.Equal(tradeIDs, []int64{3, 4, 5, 6, 7})
+	assert.Equal(fills.Before(), "7")
+	assert.True(gock.IsDone())
+}
+
+func TestGetFillsPaginateResumeAfterEndTime(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		MatchParam("before", "72").
+		Reply(http.StatusOK).
+		BodyString(fillsJSONSpanning).
+		SetHeader("CB-BEFORE", "74").
+		SetHeader("CB-AFTER", "73")
+
+	endTime := time.Date(2014, time.November, 7, 0, 0, 0, 0, time.UTC)
+	fills := accessInfo.GetFills().Paginate(gdax.PageOptions{Cursor: "72", Direction: gdax.Newer, EndTime: endTime})
+	collected, err := fills.Collect(context.Background())
+	assert.NoError(err)
+	assert.Len(collected, 1)
+	assert.Equal(collected[0].TradeID, int64(73))
+	// trade 74 was cut by EndTime, so resuming Newer must not start after it.
+	assert.Equal(fills.Before(), "73")
+
+	// walking Older from the newest page with EndTime also resumes before the cut fill.
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		Reply(http.StatusOK).
+		BodyString(fillsJSONSpanning).
+		SetHeader("CB-BEFORE", "74").
+		SetHeader("CB-AFTER", "73")
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		MatchParam("after", "73").
+		Reply(http.StatusOK).
+		BodyString("[]")
+
+	fills = accessInfo.GetFills().Paginate(gdax.PageOptions{EndTime: endTime})
+	collected, err = fills.Collect(context.Background())
+	assert.NoError(err)
+	assert.Len(collected, 1)
+	assert.Equal(fills.Before(), "73")
+
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		MatchParam("before", "73").
+		Reply(http.StatusOK).
+		BodyString(fillJSON1).
+		SetHeader("CB-BEFORE", "74").
+		SetHeader("CB-AFTER", "74")
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		MatchParam("before", "74").
+		Reply(http.StatusOK).
+		BodyString("[]")
+
+	resumed, err := accessInfo.GetFills().Paginate(gdax.PageOptions{Cursor: fills.Before(), Direction: gdax.Newer}).Collect(context.Background())
+	assert.NoError(err)
+	assert.Len(resumed, 1)
+	assert.Equal(resumed[0].TradeID, int64(74))
+	assert.True(gock.IsDone())
+}
+
+func TestGetFillsPaginateUnknownDirection(t *testing.T) {
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	_, err = accessInfo.GetFills().Paginate(gdax.PageOptions{Direction: "newest"}).Next(context.Background())
+	assert.EqualError(err, `unknown direction "newest" (expected "older" or "newer")`)
+}
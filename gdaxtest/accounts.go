@@ -0,0 +1,152 @@
+package gdaxtest
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ljeabmreosn/gdax"
+)
+
+// epsilon is the smallest amount that is not treated as zero.
+const epsilon = 1e-9
+
+// An account is the account holder's account of a single currency with its ledger and active holds.
+type account struct {
+	gdax.Account
+	ledger []*gdax.AccountHistory
+	holds  []*hold
+}
+
+// A hold reserves part of an account's balance for an open order.
+type hold struct {
+	gdax.AccountHold
+	seq     int64
+	account *account
+}
+
+// account gets the account of the specified currency, creating it if needed.
+func (exchange *Exchange) account(currency string) *account {
+	if a, ok := exchange.currencies[currency]; ok {
+		return a
+	}
+	id := uuid.New()
+	a := &account{Account: gdax.Account{ID: &id, Currency: currency, ProfileID: exchange.profileID}}
+	exchange.accounts = append(exchange.accounts, a)
+	exchange.currencies[currency] = a
+	return a
+}
+
+// post adds an entry to the ledger of an account and updates its balance.
+func (exchange *Exchange) post(a *account, entryType string, amount float64, details gdax.AccountHistoryDetails) {
+	exchange.ledgerID++
+	a.Balance += amount
+	a.Available = a.Balance - a.Holds
+	a.ledger = append(a.ledger, &gdax.AccountHistory{
+		ID:        exchange.ledgerID,
+		CreatedAt: exchange.now(),
+		Amount:    amount,
+		Balance:   a.Balance,
+		Type:      entryType,
+		Details:   details,
+	})
+}
+
+// hold reserves the specified amount of an account for an order.
+func (exchange *Exchange) hold(a *account, o *order, amount float64) {
+	exchange.seq++
+	id := uuid.New()
+	now := exchange.now()
+	h := &hold{
+		AccountHold: gdax.AccountHold{
+			ID:        &id,
+			AccountID: a.ID,
+			CreatedAt: now,
+			UpdatedAt: now,
+			Amount:    amount,
+			Type:      "order",
+			Ref:       o.ID.String(),
+		},
+		seq:     exchange.seq,
+		account: a,
+	}
+	a.holds = append(a.holds, h)
+	a.Holds += amount
+	a.Available = a.Balance - a.Holds
+	o.hold = h
+}
+
+// release releases up to the specified amount of the hold of an order. The hold is removed once it is used up.
+func (exchange *Exchange) release(o *order, amount float64) {
+	h := o.hold
+	if h == nil {
+		return
+	}
+	amount = math.Min(amount, h.Amount)
+	h.Amount -= amount
+	h.UpdatedAt = exchange.now()
+	a := h.account
+	a.Holds -= amount
+	if h.Amount <= epsilon {
+		a.Holds -= h.Amount
+		for idx, other := range a.holds {
+			if other == h {
+				a.holds = append(a.holds[:idx:idx], a.holds[idx+1:]...)
+				break
+			}
+		}
+		o.hold = nil
+	}
+	if a.Holds < epsilon {
+		a.Holds = 0
+	}
+	a.Available = a.Balance - a.Holds
+}
+
+// findAccount gets the account with the specified ID, writing an error if there is none.
+func (exchange *Exchange) findAccount(w http.ResponseWriter, accountID string) (*account, bool) {
+	for _, a := range exchange.accounts {
+		if a.ID.String() == accountID {
+			return a, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "NotFound")
+	return nil, false
+}
+
+// getTime handles GET /time.
+func (exchange *Exchange) getTime(w http.ResponseWriter) {
+	now := exchange.now()
+	writeJSON(w, http.StatusOK, gdax.ServerTime{ISO: &now, Epoch: float64(now.UnixNano()) / float64(time.Second)})
+}
+
+// getAccounts handles GET /accounts.
+func (exchange *Exchange) getAccounts(w http.ResponseWriter) {
+	accounts := make([]gdax.Account, len(exchange.accounts))
+	for idx, a := range exchange.accounts {
+		accounts[idx] = a.Account
+	}
+	writeJSON(w, http.StatusOK, accounts)
+}
+
+// getAccount handles GET /accounts/<account-id>.
+func (exchange *Exchange) getAccount(w http.ResponseWriter, accountID string) {
+	if a, ok := exchange.findAccount(w, accountID); ok {
+		writeJSON(w, http.StatusOK, a.Account)
+	}
+}
+
+// getLedger handles GET /accounts/<account-id>/ledger.
+func (exchange *Exchange) getLedger(w http.ResponseWriter, r *http.Request, accountID string) {
+	if a, ok := exchange.findAccount(w, accountID); ok {
+		paginate(w, r, exchange.config.PageLimit, a.ledger, func(entry *gdax.AccountHistory) int64 { return entry.ID })
+	}
+}
+
+// getHolds handles GET /accounts/<account-id>/holds.
+func (exchange *Exchange) getHolds(w http.ResponseWriter, r *http.Request, accountID string) {
+	if a, ok := exchange.findAccount(w, accountID); ok {
+		paginate(w, r, exchange.config.PageLimit, a.holds, func(h *hold) int64 { return h.seq })
+	}
+}
@@ -0,0 +1,368 @@
+// Package gdaxtest provides a stateful, in-process mock of the GDAX exchange for integration tests.
+//
+// An Exchange serves the REST API and the websocket feed from a single httptest.Server. Unlike stubbing individual
+// URLs, it keeps state: it holds the balances of a single account holder, accepts and matches orders, generates fills,
+// holds, and ledger entries, paginates with CB-BEFORE and CB-AFTER, verifies request signatures, and publishes the
+// resulting matches, tickers, and level 2 updates to feed subscribers.
+//
+//	exchange := gdaxtest.NewExchange(gdaxtest.Config{Products: []string{"BTC-USD"}})
+//	defer exchange.Close()
+//	exchange.Deposit("USD", 1000)
+//	accessInfo, err := exchange.AccessInfo()
+//	...
+//	stream, err := gdax.Stream(ctx, subscription, &gdax.StreamOptions{EndPoint: exchange.FeedURL()})
+//
+// Other traders are simulated with PlaceExternalOrder, whose orders match against the account holder's orders but
+// do not affect the account holder's balances.
+package gdaxtest
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	ws "github.com/gorilla/websocket"
+	"github.com/ljeabmreosn/gdax"
+)
+
+// DefaultPageLimit is the number of elements per page if neither the Config nor the request specifies a limit.
+const DefaultPageLimit = 100
+
+// maxPageLimit is the largest number of elements per page that a request can ask for.
+const maxPageLimit = 1000
+
+// timestampTolerance is how far the timestamp of a signed request can be from the exchange's clock.
+const timestampTolerance = 30 * time.Second
+
+// A Config configures an Exchange.
+type Config struct {
+	// Products are the IDs of the products that can be traded, in the form BASE-QUOTE (e.g., "BTC-USD").
+	Products []string
+	// MakerFeeRate and TakerFeeRate are the fees charged in the quote currency, as fractions of the executed value.
+	MakerFeeRate float64
+	TakerFeeRate float64
+	// PageLimit is the number of elements per page if the request does not specify a limit.
+	// If zero, DefaultPageLimit is used.
+	PageLimit int
+	// HeartbeatInterval is how often heartbeats are sent to subscribers of the heartbeat channel.
+	// If zero, heartbeats are sent every second.
+	HeartbeatInterval time.Duration
+	// Now is the clock of the exchange, which timestamps every order, fill, and ledger entry and bounds the
+	// timestamps of signed requests. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// An Exchange is a stateful mock of the exchange, served by an httptest.Server.
+// All of its methods are safe for concurrent use.
+type Exchange struct {
+	server      *httptest.Server
+	config      Config
+	credentials gdax.Credentials
+	signer      *gdax.HMACSigner
+	profileID   string
+	stop        chan struct{}
+	stopped     sync.WaitGroup
+
+	mu          sync.Mutex
+	accounts    []*account
+	currencies  map[string]*account
+	products    map[string]*product
+	orders      []*order
+	fills       []*fill
+	seq         int64
+	ledgerID    int64
+	subscribers map[*subscriber]struct{}
+}
+
+// NewExchange starts an Exchange with the specified configuration and random credentials.
+// Every currency of the products has an account with a zero balance; fund it with Deposit.
+// The Exchange must be closed with Close.
+func NewExchange(config Config) *Exchange {
+	if config.PageLimit <= 0 {
+		config.PageLimit = DefaultPageLimit
+	}
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = time.Second
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	credentials := gdax.Credentials{
+		PublicKey:  hex.EncodeToString(randomBytes(16)),
+		PrivateKey: base64.StdEncoding.EncodeToString(randomBytes(64)),
+		Passphrase: hex.EncodeToString(randomBytes(8)),
+	}
+	signer, err := gdax.NewHMACSigner(credentials.PrivateKey)
+	if err != nil {
+		panic(err)
+	}
+	exchange := &Exchange{
+		config:      config,
+		credentials: credentials,
+		signer:      signer,
+		profileID:   uuid.New().String(),
+		stop:        make(chan struct{}),
+		currencies:  make(map[string]*account),
+		products:    make(map[string]*product),
+		subscribers: make(map[*subscriber]struct{}),
+	}
+	for _, productID := range config.Products {
+		base, quote, ok := strings.Cut(productID, "-")
+		if !ok {
+			panic(fmt.Sprintf("gdaxtest: product %q is not of the form BASE-QUOTE", productID))
+		}
+		exchange.products[productID] = &product{id: productID, base: base, quote: quote}
+		exchange.account(base)
+		exchange.account(quote)
+	}
+	exchange.server = httptest.NewServer(exchange)
+	exchange.stopped.Add(1)
+	go exchange.heartbeats()
+	return exchange
+}
+
+// randomBytes gets n cryptographically random bytes.
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// URL gets the URL of the REST API, for use with gdax.WithEndPoint.
+func (exchange *Exchange) URL() string {
+	return exchange.server.URL
+}
+
+// FeedURL gets the URL of the websocket feed, for use as gdax.StreamOptions.EndPoint.
+func (exchange *Exchange) FeedURL() string {
+	return "ws" + strings.TrimPrefix(exchange.server.URL, "http")
+}
+
+// Credentials gets the credentials of the account holder.
+func (exchange *Exchange) Credentials() gdax.Credentials {
+	return exchange.credentials
+}
+
+// AccessInfo creates an AccessInfo that signs requests with the account holder's credentials and sends them to the
+// Exchange. The options are applied after the endpoint is set.
+func (exchange *Exchange) AccessInfo(options ...gdax.ClientOption) (*gdax.AccessInfo, error) {
+	options = append([]gdax.ClientOption{gdax.WithEndPoint(exchange.URL())}, options...)
+	return gdax.NewAccessInfo(exchange.credentials.PublicKey, exchange.credentials.PrivateKey, exchange.credentials.Passphrase, options...)
+}
+
+// Deposit credits the account holder's account of the specified currency with a transfer (or, if the amount is
+// negative, debits it). The account is created if the currency is not traded by any product.
+func (exchange *Exchange) Deposit(currency string, amount float64) {
+	exchange.mu.Lock()
+	defer exchange.mu.Unlock()
+	exchange.post(exchange.account(currency), gdax.TransferEntry, amount, gdax.AccountHistoryDetails{})
+}
+
+// Account gets the account holder's account of the specified currency.
+func (exchange *Exchange) Account(currency string) (gdax.Account, bool) {
+	exchange.mu.Lock()
+	defer exchange.mu.Unlock()
+	a, ok := exchange.currencies[currency]
+	if !ok {
+		return gdax.Account{}, false
+	}
+	return a.Account, true
+}
+
+// PlaceExternalOrder places an order on behalf of another trader, e.g., to provide liquidity or to fill the account
+// holder's resting orders. The order is matched and published like any other order, but it is not subject to
+// balance checks, does not affect the account holder's balances, and is not returned by the REST API.
+func (exchange *Exchange) PlaceExternalOrder(request gdax.Order) (*gdax.Order, error) {
+	exchange.mu.Lock()
+	defer exchange.mu.Unlock()
+	o, err := exchange.placeOrder(request, true)
+	if err != nil {
+		return nil, err
+	}
+	placed := o.Order
+	return &placed, nil
+}
+
+// Close disconnects every feed subscriber and shuts down the server.
+func (exchange *Exchange) Close() {
+	close(exchange.stop)
+	exchange.stopped.Wait()
+	exchange.mu.Lock()
+	for sub := range exchange.subscribers {
+		exchange.drop(sub)
+	}
+	exchange.mu.Unlock()
+	exchange.server.Close()
+}
+
+// now gets the current time of the exchange.
+func (exchange *Exchange) now() time.Time {
+	return exchange.config.Now()
+}
+
+// ServeHTTP serves the REST API and, for websocket upgrades, the feed.
+func (exchange *Exchange) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if ws.IsWebSocketUpgrade(r) {
+		exchange.serveFeed(w, r)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	// the time and product endpoints are public.
+	if path[0] != "time" && path[0] != "products" {
+		err := exchange.verify(r.Header.Get("CB-ACCESS-KEY"), r.Header.Get("CB-ACCESS-PASSPHRASE"),
+			r.Header.Get("CB-ACCESS-TIMESTAMP"), r.Header.Get("CB-ACCESS-SIGN"), r.Method, r.RequestURI, string(body))
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+	}
+
+	exchange.mu.Lock()
+	defer exchange.mu.Unlock()
+	switch {
+	case route(r, path, http.MethodGet, "time"):
+		exchange.getTime(w)
+	case route(r, path, http.MethodGet, "fees"):
+		writeJSON(w, http.StatusOK, gdax.Fees{MakerFeeRate: exchange.config.MakerFeeRate, TakerFeeRate: exchange.config.TakerFeeRate})
+	case route(r, path, http.MethodGet, "accounts"):
+		exchange.getAccounts(w)
+	case route(r, path, http.MethodGet, "accounts", "*"):
+		exchange.getAccount(w, path[1])
+	case route(r, path, http.MethodGet, "accounts", "*", "ledger"):
+		exchange.getLedger(w, r, path[1])
+	case route(r, path, http.MethodGet, "accounts", "*", "holds"):
+		exchange.getHolds(w, r, path[1])
+	case route(r, path, http.MethodPost, "orders"):
+		exchange.postOrder(w, body)
+	case route(r, path, http.MethodDelete, "orders"):
+		exchange.deleteOrders(w, r)
+	case route(r, path, http.MethodGet, "orders"):
+		exchange.getOrders(w, r)
+	case route(r, path, http.MethodGet, "orders", "*"):
+		exchange.getOrder(w, path[1])
+	case route(r, path, http.MethodGet, "fills"):
+		exchange.getFills(w, r)
+	case route(r, path, http.MethodGet, "products", "*", "trades"):
+		exchange.getTrades(w, r, path[1])
+	default:
+		writeError(w, http.StatusNotFound, "NotFound")
+	}
+}
+
+// route determines if a request has the specified method and path segments, where "*" matches any segment.
+func route(r *http.Request, path []string, method string, segments ...string) bool {
+	if r.Method != method || len(path) != len(segments) {
+		return false
+	}
+	for idx, segment := range segments {
+		if segment != "*" && segment != path[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// verify checks the credentials and signature of a request (or of an authenticated subscription).
+func (exchange *Exchange) verify(key, passphrase, timestamp, signature, method, requestPath, body string) error {
+	if key != exchange.credentials.PublicKey {
+		return errors.New("invalid API key")
+	}
+	if passphrase != exchange.credentials.Passphrase {
+		return errors.New("invalid passphrase")
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid timestamp")
+	}
+	if skew := exchange.now().Sub(time.Unix(seconds, 0)); skew > timestampTolerance || skew < -timestampTolerance {
+		return errors.New("request timestamp expired")
+	}
+	expected, err := exchange.signer.Sign(timestamp, method, requestPath, body)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// writeJSON writes a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response in the format of the exchange.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+// paginate writes a page of items, which must be sorted by ascending cursor, newest first.
+// As on the exchange, "after" pages toward older items, "before" pages toward newer items, and the CB-BEFORE and
+// CB-AFTER headers are the cursors of the newest and oldest items of the page.
+func paginate[T any](w http.ResponseWriter, r *http.Request, defaultLimit int, items []T, cursor func(T) int64) {
+	query := r.URL.Query()
+	limit := defaultLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxPageLimit {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	var page []T
+	switch {
+	case query.Get("before") != "":
+		before, err := strconv.ParseInt(query.Get("before"), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid before cursor")
+			return
+		}
+		start := sort.Search(len(items), func(i int) bool { return cursor(items[i]) > before })
+		page = items[start:min(start+limit, len(items))]
+	case query.Get("after") != "":
+		after, err := strconv.ParseInt(query.Get("after"), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid after cursor")
+			return
+		}
+		end := sort.Search(len(items), func(i int) bool { return cursor(items[i]) >= after })
+		page = items[max(end-limit, 0):end]
+	default:
+		page = items[max(len(items)-limit, 0):]
+	}
+
+	newestFirst := make([]T, len(page))
+	for idx, item := range page {
+		newestFirst[len(page)-1-idx] = item
+	}
+	if len(page) > 0 {
+		w.Header().Set("CB-BEFORE", strconv.FormatInt(cursor(page[len(page)-1]), 10))
+		w.Header().Set("CB-AFTER", strconv.FormatInt(cursor(page[0]), 10))
+	}
+	writeJSON(w, http.StatusOK, newestFirst)
+}
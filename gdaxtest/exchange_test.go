@@ -0,0 +1,205 @@
+package gdaxtest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ljeabmreosn/gdax"
+	"github.com/ljeabmreosn/gdax/gdaxtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExchangeOrders(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	exchange := gdaxtest.NewExchange(gdaxtest.Config{Products: []string{"BTC-USD"}, TakerFeeRate: 0.01})
+	defer exchange.Close()
+	exchange.Deposit("USD", 1000)
+	accessInfo, err := exchange.AccessInfo()
+	assert.NoError(err)
+
+	for _, price := range []float64{100, 200} {
+		_, err := exchange.PlaceExternalOrder(gdax.Order{Side: gdax.Sell, ProductID: "BTC-USD", Price: price, Size: 1})
+		assert.NoError(err)
+	}
+
+	// fills 1 BTC at 100 and rests the other 1 BTC at 150, holding 300 USD plus the taker fee.
+	order, err := accessInfo.PlaceLimitOrder(&gdax.Order{Side: gdax.Buy, ProductID: "BTC-USD", Price: 150, Size: 2})
+	assert.NoError(err)
+	assert.Equal(order.Status, gdax.Open)
+	assert.Equal(order.FilledSize, 1.0)
+	assert.Equal(order.FillFees, 1.0)
+
+	usd, ok := exchange.Account("USD")
+	assert.True(ok)
+	assert.InDelta(usd.Balance, 899, 1e-9)
+	assert.InDelta(usd.Holds, 202, 1e-9)
+	assert.InDelta(usd.Available, 697, 1e-9)
+
+	// another trader sells into the resting order, which is the maker and pays no fee.
+	_, err = exchange.PlaceExternalOrder(gdax.Order{Side: gdax.Sell, ProductID: "BTC-USD", Type: gdax.Market, Size: 0.5})
+	assert.NoError(err)
+	order, err = accessInfo.GetOrder(order.ID)
+	assert.NoError(err)
+	assert.Equal(order.FilledSize, 1.5)
+
+	canceled, err := accessInfo.CancelOrder(order.ID).Collect(ctx)
+	assert.NoError(err)
+	assert.Equal(canceled, []*uuid.UUID{order.ID})
+
+	accounts, err := accessInfo.GetAccounts().Collect(ctx)
+	assert.NoError(err)
+	assert.Len(accounts, 2)
+	for _, account := range accounts {
+		switch account.Currency {
+		case "BTC":
+			assert.InDelta(account.Balance, 1.5, 1e-9)
+		case "USD":
+			assert.InDelta(account.Balance, 824, 1e-9)
+			assert.Equal(account.Holds, 0.0)
+			assert.InDelta(account.Available, 824, 1e-9)
+
+			ledger, err := accessInfo.GetAccountHistory(account.ID).Collect(ctx)
+			assert.NoError(err)
+			types := make([]string, len(ledger))
+			for idx, entry := range ledger {
+				types[idx] = entry.Type
+			}
+			assert.Equal(types, []string{gdax.MatchEntry, gdax.FeeEntry, gdax.MatchEntry, gdax.TransferEntry})
+
+			holds, err := accessInfo.GetAccountHolds(account.ID).Collect(ctx)
+			assert.NoError(err)
+			assert.Empty(holds)
+		}
+	}
+
+	fills, err := accessInfo.GetFills(order.ID).Collect(ctx)
+	assert.NoError(err)
+	assert.Len(fills, 2)
+	assert.Equal(fills[0].Liquidity, gdax.Maker)
+	assert.Equal(fills[0].Price, 150.0)
+	assert.Equal(fills[1].Liquidity, gdax.Taker)
+	assert.Equal(fills[1].Fee, 1.0)
+
+	orders, err := accessInfo.GetOrders(gdax.Open).Collect(ctx)
+	assert.NoError(err)
+	assert.Empty(orders)
+
+	trades, err := accessInfo.GetTrades("BTC-USD").Collect(ctx)
+	assert.NoError(err)
+	assert.Len(trades, 2)
+	assert.Equal(trades[0].TradeID, int64(2))
+
+	_, err = accessInfo.PlaceLimitOrder(&gdax.Order{Side: gdax.Buy, ProductID: "BTC-USD", Price: 1000, Size: 1})
+	assert.EqualError(err, "Insufficient funds")
+}
+
+func TestExchangePagination(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	exchange := gdaxtest.NewExchange(gdaxtest.Config{Products: []string{"BTC-USD"}, PageLimit: 2})
+	defer exchange.Close()
+	for amount := 1; amount <= 5; amount++ {
+		exchange.Deposit("USD", float64(amount))
+	}
+	accessInfo, err := exchange.AccessInfo()
+	assert.NoError(err)
+	usd, _ := exchange.Account("USD")
+
+	ledger := accessInfo.GetAccountHistory(usd.ID)
+	entries, err := ledger.Collect(ctx)
+	assert.NoError(err)
+	amounts := make([]float64, len(entries))
+	for idx, entry := range entries {
+		amounts[idx] = entry.Amount
+	}
+	assert.Equal(amounts, []float64{5, 4, 3, 2, 1})
+
+	// resume from the third entry toward newer entries.
+	newer, err := accessInfo.GetAccountHistory(usd.ID).
+		Paginate(gdax.PageOptions{Cursor: "3", Direction: gdax.Newer}).
+		Collect(ctx)
+	assert.NoError(err)
+	// entries newer than the cursor are yielded oldest first.
+	assert.Len(newer, 2)
+	assert.Equal(newer[0].Amount, 4.0)
+	assert.Equal(newer[1].Amount, 5.0)
+}
+
+func TestExchangeSignatures(t *testing.T) {
+	assert := assert.New(t)
+
+	exchange := gdaxtest.NewExchange(gdaxtest.Config{Products: []string{"BTC-USD"}})
+	defer exchange.Close()
+	credentials := exchange.Credentials()
+
+	accessInfo, err := gdax.NewAccessInfo(credentials.PublicKey, "c2VjcmV0", credentials.Passphrase, gdax.WithEndPoint(exchange.URL()))
+	assert.NoError(err)
+	_, err = accessInfo.GetAccounts().Collect(context.Background())
+	assert.EqualError(err, "invalid signature")
+
+	accessInfo, err = gdax.NewAccessInfo(credentials.PublicKey, credentials.PrivateKey, "wrong", gdax.WithEndPoint(exchange.URL()))
+	assert.NoError(err)
+	_, err = accessInfo.GetFees()
+	assert.EqualError(err, "invalid passphrase")
+
+	// the exchange's clock is an hour ahead, so only a synchronized client is accepted.
+	skewed := gdaxtest.NewExchange(gdaxtest.Config{Now: func() time.Time { return time.Now().Add(time.Hour) }})
+	defer skewed.Close()
+	accessInfo, err = skewed.AccessInfo()
+	assert.NoError(err)
+	_, err = accessInfo.GetFees()
+	assert.EqualError(err, "request timestamp expired")
+	accessInfo, err = skewed.AccessInfo(gdax.WithClockSync(time.Minute))
+	assert.NoError(err)
+	_, err = accessInfo.GetFees()
+	assert.NoError(err)
+}
+
+func TestExchangeFeed(t *testing.T) {
+	assert := assert.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	exchange := gdaxtest.NewExchange(gdaxtest.Config{Products: []string{"BTC-USD"}})
+	defer exchange.Close()
+	_, err := exchange.PlaceExternalOrder(gdax.Order{Side: gdax.Sell, ProductID: "BTC-USD", Price: 100, Size: 1})
+	assert.NoError(err)
+
+	stream, err := gdax.Stream(ctx, &gdax.Subscription{
+		Type:       gdax.SubscribeType,
+		Channels:   []string{gdax.MatchesType, gdax.Level2Type},
+		ProductIDs: []string{"BTC-USD"},
+	}, &gdax.StreamOptions{EndPoint: exchange.FeedURL()})
+	assert.NoError(err)
+	defer stream.Close()
+
+	next := func() gdax.Message {
+		select {
+		case message := <-stream.Messages():
+			return message
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for a message")
+			return nil
+		}
+	}
+	assert.Equal(next().MessageType(), gdax.SubscriptionsType)
+	snapshot, ok := next().(gdax.Snapshot)
+	assert.True(ok)
+	assert.Equal(snapshot.Asks, []gdax.Ask{{Price: 100, Size: 1}})
+
+	_, err = exchange.PlaceExternalOrder(gdax.Order{Side: gdax.Buy, ProductID: "BTC-USD", Price: 100, Size: 0.25})
+	assert.NoError(err)
+	match, ok := next().(gdax.Match)
+	assert.True(ok)
+	assert.Equal(match.TradeID, int64(1))
+	assert.Equal(match.Price, 100.0)
+	assert.Equal(match.Side, gdax.Sell)
+	l2update, ok := next().(gdax.L2Update)
+	assert.True(ok)
+	assert.Equal(l2update.Changes, []gdax.Change{{Side: gdax.Sell, Price: 100, Size: 0.75}})
+}
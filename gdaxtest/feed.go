@@ -0,0 +1,350 @@
+package gdaxtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	ws "github.com/gorilla/websocket"
+	"github.com/ljeabmreosn/gdax"
+)
+
+// feedBufferSize is the number of frames buffered for a subscriber. A subscriber that falls further behind is
+// disconnected, as on the exchange.
+const feedBufferSize = 1024
+
+// supportedChannels are the channels that can be subscribed to.
+var supportedChannels = []string{gdax.HeartbeatType, gdax.TickerType, gdax.Level2Type, gdax.MatchesType, gdax.UserType}
+
+// A subscriber is a websocket connection to the feed.
+type subscriber struct {
+	conn          *ws.Conn
+	send          chan []byte
+	channels      map[string]map[string]bool // channel -> product IDs
+	authenticated bool
+}
+
+// A channelFrame lists the products subscribed to on a channel in a subscriptions frame.
+type channelFrame struct {
+	Name       string   `json:"name"`
+	ProductIDs []string `json:"product_ids"`
+}
+
+// A subscriptionsFrame acknowledges a subscription with the current subscriptions.
+type subscriptionsFrame struct {
+	Type     string         `json:"type"`
+	Channels []channelFrame `json:"channels"`
+}
+
+// An errorFrame reports a rejected subscription.
+type errorFrame struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+}
+
+// A heartbeatFrame is a heartbeat of a product.
+type heartbeatFrame struct {
+	Type        string    `json:"type"`
+	Sequence    int64     `json:"sequence"`
+	LastTradeID int64     `json:"last_trade_id"`
+	ProductID   string    `json:"product_id"`
+	Time        time.Time `json:"time"`
+}
+
+// A matchFrame is a trade, as sent on the matches and user channels.
+type matchFrame struct {
+	Type         string     `json:"type"`
+	TradeID      int64      `json:"trade_id"`
+	Sequence     int64      `json:"sequence"`
+	MakerOrderID *uuid.UUID `json:"maker_order_id"`
+	TakerOrderID *uuid.UUID `json:"taker_order_id"`
+	Time         time.Time  `json:"time"`
+	ProductID    string     `json:"product_id"`
+	Size         string     `json:"size"`
+	Price        string     `json:"price"`
+	Side         string     `json:"side"`
+}
+
+// A tickerFrame is the last trade and the best bid and ask after it.
+type tickerFrame struct {
+	Type      string    `json:"type"`
+	TradeID   int64     `json:"trade_id"`
+	Sequence  int64     `json:"sequence"`
+	Time      time.Time `json:"time"`
+	ProductID string    `json:"product_id"`
+	Price     string    `json:"price"`
+	Side      string    `json:"side"`
+	LastSize  string    `json:"last_size"`
+	BestBid   string    `json:"best_bid"`
+	BestAsk   string    `json:"best_ask"`
+}
+
+// A snapshotFrame is the aggregated order book of a product.
+type snapshotFrame struct {
+	Type      string     `json:"type"`
+	ProductID string     `json:"product_id"`
+	Bids      [][]string `json:"bids"`
+	Asks      [][]string `json:"asks"`
+}
+
+// An l2updateFrame is the new size of price levels of a product.
+type l2updateFrame struct {
+	Type      string     `json:"type"`
+	ProductID string     `json:"product_id"`
+	Time      time.Time  `json:"time"`
+	Changes   [][]string `json:"changes"`
+}
+
+// upgrader upgrades feed requests to websocket connections.
+var upgrader = ws.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+// number formats an amount as the exchange does in feed messages.
+func number(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// serveFeed serves a websocket connection to the feed until it is closed.
+func (exchange *Exchange) serveFeed(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	sub := &subscriber{conn: conn, send: make(chan []byte, feedBufferSize), channels: make(map[string]map[string]bool)}
+	exchange.mu.Lock()
+	exchange.subscribers[sub] = struct{}{}
+	exchange.mu.Unlock()
+	go sub.write()
+
+	defer func() {
+		exchange.mu.Lock()
+		exchange.drop(sub)
+		exchange.mu.Unlock()
+	}()
+	for {
+		var s gdax.Subscription
+		if err := conn.ReadJSON(&s); err != nil {
+			return
+		}
+		exchange.mu.Lock()
+		exchange.subscribe(sub, &s)
+		exchange.mu.Unlock()
+	}
+}
+
+// write sends the frames of a subscriber until it is dropped, then closes its connection.
+func (sub *subscriber) write() {
+	defer sub.conn.Close()
+	for frame := range sub.send {
+		if err := sub.conn.WriteMessage(ws.TextMessage, frame); err != nil {
+			return
+		}
+	}
+}
+
+// drop disconnects a subscriber. It is safe to drop a subscriber more than once.
+func (exchange *Exchange) drop(sub *subscriber) {
+	if _, ok := exchange.subscribers[sub]; !ok {
+		return
+	}
+	delete(exchange.subscribers, sub)
+	close(sub.send)
+}
+
+// push queues a frame for a subscriber, dropping the subscriber if it has fallen too far behind.
+func (exchange *Exchange) push(sub *subscriber, v interface{}) {
+	if _, ok := exchange.subscribers[sub]; !ok {
+		return
+	}
+	frame, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	select {
+	case sub.send <- frame:
+	default:
+		exchange.drop(sub)
+	}
+}
+
+// publish sends a frame to the subscribers of a channel for a product.
+func (exchange *Exchange) publish(channel, productID string, v interface{}) {
+	for sub := range exchange.subscribers {
+		if sub.channels[channel][productID] {
+			exchange.push(sub, v)
+		}
+	}
+}
+
+// subscribe applies a subscribe (or unsubscribe) message and acknowledges it with the current subscriptions.
+// A signed subscription authenticates the connection, which is required for the user channel.
+func (exchange *Exchange) subscribe(sub *subscriber, s *gdax.Subscription) {
+	reject := func(reason string) {
+		exchange.push(sub, errorFrame{Type: gdax.ErrorType, Message: "Failed to subscribe", Reason: reason})
+	}
+	if s.Signature != "" {
+		if err := exchange.verify(s.Key, s.Passphrase, s.Timestamp, s.Signature, http.MethodGet, "/users/self/verify", ""); err != nil {
+			exchange.push(sub, errorFrame{Type: gdax.ErrorType, Message: "Authentication Failed", Reason: err.Error()})
+			return
+		}
+		sub.authenticated = true
+	}
+	if s.Type != gdax.SubscribeType && s.Type != "unsubscribe" {
+		reject(fmt.Sprintf("%q is not a valid message type", s.Type))
+		return
+	}
+	for _, channel := range s.Channels {
+		if !slices.Contains(supportedChannels, channel) {
+			reject(fmt.Sprintf("%q is not a valid channel", channel))
+			return
+		}
+		if channel == gdax.UserType && !sub.authenticated {
+			reject("the user channel requires authentication")
+			return
+		}
+	}
+	for _, productID := range s.ProductIDs {
+		if _, ok := exchange.products[productID]; !ok {
+			reject(fmt.Sprintf("%q is not a valid product", productID))
+			return
+		}
+	}
+
+	var snapshots []snapshotFrame
+	for _, channel := range s.Channels {
+		if sub.channels[channel] == nil {
+			sub.channels[channel] = make(map[string]bool)
+		}
+		for _, productID := range s.ProductIDs {
+			if s.Type == "unsubscribe" {
+				delete(sub.channels[channel], productID)
+				continue
+			}
+			if channel == gdax.Level2Type && !sub.channels[channel][productID] {
+				snapshots = append(snapshots, exchange.snapshot(exchange.products[productID]))
+			}
+			sub.channels[channel][productID] = true
+		}
+	}
+	ack := subscriptionsFrame{Type: gdax.SubscriptionsType, Channels: []channelFrame{}}
+	for _, channel := range supportedChannels {
+		var productIDs []string
+		for _, productID := range exchange.config.Products {
+			if sub.channels[channel][productID] {
+				productIDs = append(productIDs, productID)
+			}
+		}
+		if productIDs != nil {
+			ack.Channels = append(ack.Channels, channelFrame{Name: channel, ProductIDs: productIDs})
+		}
+	}
+	exchange.push(sub, ack)
+	for _, snapshot := range snapshots {
+		exchange.push(sub, snapshot)
+	}
+}
+
+// snapshot gets the aggregated order book of a product.
+func (exchange *Exchange) snapshot(p *product) snapshotFrame {
+	return snapshotFrame{Type: gdax.SnapshotType, ProductID: p.id, Bids: aggregate(p.bids), Asks: aggregate(p.asks)}
+}
+
+// aggregate sums the sizes of the orders of one side of a book at each price, best price first.
+func aggregate(book []*order) [][]string {
+	levels := [][]string{}
+	var price, size float64
+	for idx, o := range book {
+		if idx > 0 && o.Price != price {
+			levels = append(levels, []string{number(price), number(size)})
+			size = 0
+		}
+		price = o.Price
+		size += o.remaining
+	}
+	if len(book) > 0 {
+		levels = append(levels, []string{number(price), number(size)})
+	}
+	return levels
+}
+
+// publishLevel publishes the new size of a price level of a product on the level2 channel.
+func (exchange *Exchange) publishLevel(p *product, side string, price float64) {
+	exchange.publish(gdax.Level2Type, p.id, l2updateFrame{
+		Type:      gdax.L2UpdateType,
+		ProductID: p.id,
+		Time:      exchange.now(),
+		Changes:   [][]string{{side, number(price), number(p.level(side, price))}},
+	})
+}
+
+// publishMatch publishes a trade on the matches and ticker channels and, if it involves one of the account holder's
+// orders, on the user channel.
+func (exchange *Exchange) publishMatch(p *product, trade *gdax.Trade, maker, taker *order) {
+	p.sequence++
+	match := matchFrame{
+		Type:         gdax.MatchType,
+		TradeID:      trade.TradeID,
+		Sequence:     p.sequence,
+		MakerOrderID: maker.ID,
+		TakerOrderID: taker.ID,
+		Time:         *trade.Time,
+		ProductID:    p.id,
+		Size:         number(trade.Size),
+		Price:        number(trade.Price),
+		Side:         trade.Side,
+	}
+	exchange.publish(gdax.MatchesType, p.id, match)
+	if !maker.external || !taker.external {
+		exchange.publish(gdax.UserType, p.id, match)
+	}
+
+	ticker := tickerFrame{
+		Type:      gdax.TickerType,
+		TradeID:   trade.TradeID,
+		Sequence:  p.sequence,
+		Time:      *trade.Time,
+		ProductID: p.id,
+		Price:     number(trade.Price),
+		Side:      taker.Side,
+		LastSize:  number(trade.Size),
+		BestBid:   "0",
+		BestAsk:   "0",
+	}
+	if len(p.bids) > 0 {
+		ticker.BestBid = number(p.bids[0].Price)
+	}
+	if len(p.asks) > 0 {
+		ticker.BestAsk = number(p.asks[0].Price)
+	}
+	exchange.publish(gdax.TickerType, p.id, ticker)
+}
+
+// heartbeats publishes a heartbeat of every product at the heartbeat interval until the Exchange is closed.
+func (exchange *Exchange) heartbeats() {
+	defer exchange.stopped.Done()
+	ticker := time.NewTicker(exchange.config.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exchange.stop:
+			return
+		case <-ticker.C:
+		}
+		exchange.mu.Lock()
+		for _, productID := range exchange.config.Products {
+			p := exchange.products[productID]
+			exchange.publish(gdax.HeartbeatType, productID, heartbeatFrame{
+				Type:        gdax.HeartbeatType,
+				Sequence:    p.sequence,
+				LastTradeID: int64(len(p.trades)),
+				ProductID:   productID,
+				Time:        exchange.now(),
+			})
+		}
+		exchange.mu.Unlock()
+	}
+}
@@ -0,0 +1,454 @@
+package gdaxtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ljeabmreosn/gdax"
+)
+
+// A product is a traded product with its order book and trades.
+type product struct {
+	id       string
+	base     string
+	quote    string
+	bids     []*order // best (highest) price first, then oldest first
+	asks     []*order // best (lowest) price first, then oldest first
+	trades   []*gdax.Trade
+	sequence int64
+}
+
+// An order is an order of the account holder or of an external trader.
+type order struct {
+	gdax.Order
+	seq       int64
+	external  bool
+	remaining float64 // the size that is not filled yet (infinite for buys by funds)
+	funds     float64 // the funds that are not spent yet (buys by funds)
+	hold      *hold
+}
+
+// A fill is a fill of one of the account holder's orders.
+type fill struct {
+	gdax.Fill
+	seq int64
+}
+
+// book gets the side of the order book of a product that rests orders of the specified side.
+func (p *product) book(side string) *[]*order {
+	if side == gdax.Buy {
+		return &p.bids
+	}
+	return &p.asks
+}
+
+// opposite gets the side of the order book of a product that an order of the specified side matches against.
+func (p *product) opposite(side string) *[]*order {
+	if side == gdax.Buy {
+		return &p.asks
+	}
+	return &p.bids
+}
+
+// level gets the total size resting at a price on one side of the order book of a product.
+func (p *product) level(side string, price float64) float64 {
+	var size float64
+	for _, o := range *p.book(side) {
+		if o.Price == price {
+			size += o.remaining
+		}
+	}
+	return size
+}
+
+// crosses determines if an order can match against a resting order at the specified price.
+func (o *order) crosses(price float64) bool {
+	if o.Type == gdax.Market {
+		return true
+	}
+	if o.Side == gdax.Buy {
+		return o.Price >= price
+	}
+	return o.Price <= price
+}
+
+// validateOrder checks an order request and fills in the defaults of its type and time in force.
+func (exchange *Exchange) validateOrder(request *gdax.Order) error {
+	if _, ok := exchange.products[request.ProductID]; !ok {
+		return fmt.Errorf("product %q not found", request.ProductID)
+	}
+	if request.Side != gdax.Buy && request.Side != gdax.Sell {
+		return fmt.Errorf("invalid side %q", request.Side)
+	}
+	if request.Type == "" {
+		request.Type = gdax.Limit
+	}
+	switch request.Type {
+	case gdax.Limit:
+		if request.Price <= 0 || request.Size <= 0 {
+			return errors.New("limit orders require a positive price and size")
+		}
+		switch request.TimeInForce {
+		case "":
+			request.TimeInForce = gdax.GoodTillCancelled
+		case gdax.GoodTillCancelled, gdax.GoodTillTime, gdax.ImmediateOrCancel, gdax.FillOrKill:
+		default:
+			return fmt.Errorf("invalid time_in_force %q", request.TimeInForce)
+		}
+		if request.PostOnly && (request.TimeInForce == gdax.ImmediateOrCancel || request.TimeInForce == gdax.FillOrKill) {
+			return errors.New("post_only is invalid with IOC or FOK")
+		}
+	case gdax.Market:
+		if (request.Size > 0) == (request.Funds > 0) {
+			return errors.New("market orders require either size or funds")
+		}
+		if request.Funds > 0 && request.Side == gdax.Sell {
+			return errors.New("market sell orders require size")
+		}
+		if request.PostOnly {
+			return errors.New("post_only is invalid for market orders")
+		}
+	default:
+		return fmt.Errorf("invalid type %q", request.Type)
+	}
+	return nil
+}
+
+// placeOrder validates an order, holds the funds it needs, and matches it. If it is not filled, a good till
+// cancelled (or good till time) limit order rests on the book; any other order is done.
+func (exchange *Exchange) placeOrder(request gdax.Order, external bool) (*order, error) {
+	if err := exchange.validateOrder(&request); err != nil {
+		return nil, err
+	}
+	p := exchange.products[request.ProductID]
+
+	exchange.seq++
+	id := uuid.New()
+	now := exchange.now()
+	o := &order{Order: request, seq: exchange.seq, external: external, remaining: request.Size, funds: request.Funds}
+	o.ID = &id
+	o.CreatedAt = &now
+	o.Status = gdax.Pending
+	o.FillFees, o.FilledSize, o.ExecutedValue, o.Settled = 0, 0, 0, false
+	if o.Funds > 0 {
+		o.remaining = math.Inf(1)
+	}
+
+	book := *p.opposite(o.Side)
+	if o.PostOnly && len(book) > 0 && o.crosses(book[0].Price) {
+		return nil, errors.New("post only order would take liquidity")
+	}
+	if !external {
+		if err := exchange.holdFor(o, p); err != nil {
+			return nil, err
+		}
+		exchange.orders = append(exchange.orders, o)
+	}
+	if o.TimeInForce == gdax.FillOrKill && exchange.liquidity(o, p) < o.Size-epsilon {
+		exchange.finish(o)
+		return o, nil
+	}
+
+	exchange.match(o, p)
+	resting := o.TimeInForce == gdax.GoodTillCancelled || o.TimeInForce == gdax.GoodTillTime
+	if o.Type == gdax.Limit && resting && o.remaining > epsilon {
+		exchange.rest(o, p)
+	} else {
+		exchange.finish(o)
+	}
+	return o, nil
+}
+
+// holdFor holds the funds that an order of the account holder needs, which must be available.
+// Buys hold the quote currency including the taker fee; sells hold the base currency.
+func (exchange *Exchange) holdFor(o *order, p *product) error {
+	currency, amount := p.base, o.Size
+	if o.Side == gdax.Buy {
+		currency = p.quote
+		switch {
+		case o.Funds > 0:
+			amount = o.Funds
+		case o.Type == gdax.Limit:
+			amount = o.Price * o.Size * (1 + exchange.config.TakerFeeRate)
+		default:
+			amount = exchange.cost(o, p)
+		}
+	}
+	a := exchange.account(currency)
+	if amount > a.Available+epsilon {
+		return errors.New("Insufficient funds")
+	}
+	if amount > 0 {
+		exchange.hold(a, o, amount)
+	}
+	return nil
+}
+
+// cost estimates the funds that a market buy by size needs by walking the asks.
+func (exchange *Exchange) cost(o *order, p *product) float64 {
+	var cost float64
+	remaining := o.Size
+	for _, ask := range p.asks {
+		size := math.Min(remaining, ask.remaining)
+		cost += size * ask.Price * (1 + exchange.config.TakerFeeRate)
+		if remaining -= size; remaining <= epsilon {
+			break
+		}
+	}
+	return cost
+}
+
+// liquidity gets the total size that an order can match against.
+func (exchange *Exchange) liquidity(o *order, p *product) float64 {
+	var size float64
+	for _, resting := range *p.opposite(o.Side) {
+		if !o.crosses(resting.Price) {
+			break
+		}
+		size += resting.remaining
+	}
+	return size
+}
+
+// match matches an order against the opposite side of the book, best price first, until it is filled or no longer
+// crosses the book.
+func (exchange *Exchange) match(taker *order, p *product) {
+	book := p.opposite(taker.Side)
+	for taker.remaining > epsilon && len(*book) > 0 {
+		maker := (*book)[0]
+		if !taker.crosses(maker.Price) {
+			break
+		}
+		size := math.Min(taker.remaining, maker.remaining)
+		if taker.Funds > 0 {
+			size = math.Min(size, taker.funds/(maker.Price*(1+exchange.config.TakerFeeRate)))
+		}
+		if size <= epsilon {
+			break
+		}
+		exchange.execute(p, maker, taker, size)
+	}
+}
+
+// execute trades the specified size between the best resting maker order and a taker order at the maker's price.
+// The maker order is removed from the book once it is filled.
+func (exchange *Exchange) execute(p *product, maker, taker *order, size float64) {
+	tradeID := int64(len(p.trades) + 1)
+	now := exchange.now()
+	price := maker.Price
+	exchange.fill(p, maker, tradeID, size, price, size*price*exchange.config.MakerFeeRate, gdax.Maker)
+	exchange.fill(p, taker, tradeID, size, price, size*price*exchange.config.TakerFeeRate, gdax.Taker)
+	trade := &gdax.Trade{TradeID: tradeID, Time: &now, Price: price, Size: size, Side: maker.Side}
+	p.trades = append(p.trades, trade)
+	if maker.remaining <= epsilon {
+		book := p.book(maker.Side)
+		*book = (*book)[1:]
+		exchange.finish(maker)
+	}
+	exchange.publishMatch(p, trade, maker, taker)
+	exchange.publishLevel(p, maker.Side, maker.Price)
+}
+
+// fill updates one side of a trade and, for the account holder's orders, settles it and records the fill.
+func (exchange *Exchange) fill(p *product, o *order, tradeID int64, size, price, fee float64, liquidity string) {
+	value := size * price
+	o.remaining -= size
+	o.FilledSize += size
+	o.ExecutedValue += value
+	o.FillFees += fee
+	if o.Funds > 0 {
+		o.funds -= value + fee
+	}
+	if o.external {
+		return
+	}
+
+	base, quote := exchange.account(p.base), exchange.account(p.quote)
+	details := gdax.AccountHistoryDetails{OrderID: o.ID, TradeID: strconv.FormatInt(tradeID, 10), ProductID: p.id}
+	if o.Side == gdax.Buy {
+		exchange.release(o, value+fee)
+		exchange.post(base, gdax.MatchEntry, size, details)
+		exchange.post(quote, gdax.MatchEntry, -value, details)
+	} else {
+		exchange.release(o, size)
+		exchange.post(base, gdax.MatchEntry, -size, details)
+		exchange.post(quote, gdax.MatchEntry, value, details)
+	}
+	if fee > 0 {
+		exchange.post(quote, gdax.FeeEntry, -fee, details)
+	}
+
+	exchange.seq++
+	createdAt := exchange.now()
+	exchange.fills = append(exchange.fills, &fill{
+		Fill: gdax.Fill{
+			TradeID:   tradeID,
+			ProductID: p.id,
+			Price:     price,
+			Size:      size,
+			OrderID:   o.ID,
+			CreatedAt: &createdAt,
+			Liquidity: liquidity,
+			Fee:       fee,
+			Settled:   true,
+			Side:      o.Side,
+		},
+		seq: exchange.seq,
+	})
+}
+
+// rest adds an order to its side of the book behind the orders at the same or a better price.
+func (exchange *Exchange) rest(o *order, p *product) {
+	o.Status = gdax.Open
+	book := p.book(o.Side)
+	idx := sort.Search(len(*book), func(i int) bool {
+		if o.Side == gdax.Buy {
+			return (*book)[i].Price < o.Price
+		}
+		return (*book)[i].Price > o.Price
+	})
+	*book = append(*book, nil)
+	copy((*book)[idx+1:], (*book)[idx:])
+	(*book)[idx] = o
+	exchange.publishLevel(p, o.Side, o.Price)
+}
+
+// finish marks an order as done and releases what is left of its hold.
+func (exchange *Exchange) finish(o *order) {
+	o.Status = gdax.Done
+	o.Settled = true
+	exchange.release(o, math.Inf(1))
+}
+
+// cancel removes an open order from the book.
+func (exchange *Exchange) cancel(o *order) {
+	p := exchange.products[o.ProductID]
+	book := p.book(o.Side)
+	for idx, resting := range *book {
+		if resting == o {
+			*book = append((*book)[:idx:idx], (*book)[idx+1:]...)
+			break
+		}
+	}
+	exchange.finish(o)
+	exchange.publishLevel(p, o.Side, o.Price)
+}
+
+// findOrder gets the account holder's order with the specified ID.
+func (exchange *Exchange) findOrder(orderID string) (*order, bool) {
+	for _, o := range exchange.orders {
+		if o.ID.String() == orderID {
+			return o, true
+		}
+	}
+	return nil, false
+}
+
+// postOrder handles POST /orders.
+func (exchange *Exchange) postOrder(w http.ResponseWriter, body []byte) {
+	var request gdax.Order
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid order: "+err.Error())
+		return
+	}
+	o, err := exchange.placeOrder(request, false)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, o.Order)
+}
+
+// deleteOrders handles DELETE /orders, which cancels the open orders with the order_id and product_id parameters.
+func (exchange *Exchange) deleteOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	orderID, productID := query.Get("order_id"), query.Get("product_id")
+	if orderID != "" {
+		if o, ok := exchange.findOrder(orderID); !ok || o.Status != gdax.Open {
+			writeError(w, http.StatusNotFound, "order not found")
+			return
+		}
+	}
+	canceled := []*uuid.UUID{}
+	for _, o := range exchange.orders {
+		if o.Status != gdax.Open || (orderID != "" && o.ID.String() != orderID) || (productID != "" && o.ProductID != productID) {
+			continue
+		}
+		exchange.cancel(o)
+		canceled = append(canceled, o.ID)
+	}
+	writeJSON(w, http.StatusOK, canceled)
+}
+
+// getOrders handles GET /orders, which lists the orders with the status and product_id parameters.
+// As on the exchange, only open, pending, and active orders are listed if there is no status parameter.
+func (exchange *Exchange) getOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	statuses := query["status"]
+	if len(statuses) == 0 {
+		statuses = []string{gdax.Open, gdax.Pending, gdax.Active}
+	}
+	productID := query.Get("product_id")
+	var orders []*order
+	for _, o := range exchange.orders {
+		if productID != "" && o.ProductID != productID {
+			continue
+		}
+		for _, status := range statuses {
+			if status == gdax.All || status == o.Status {
+				orders = append(orders, o)
+				break
+			}
+		}
+	}
+	paginate(w, r, exchange.config.PageLimit, orders, func(o *order) int64 { return o.seq })
+}
+
+// getOrder handles GET /orders/<order-id>.
+func (exchange *Exchange) getOrder(w http.ResponseWriter, orderID string) {
+	o, ok := exchange.findOrder(orderID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound")
+		return
+	}
+	writeJSON(w, http.StatusOK, o.Order)
+}
+
+// getFills handles GET /fills, which lists the fills with the order_id and product_id parameters.
+func (exchange *Exchange) getFills(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var orderIDs []string
+	if param := query.Get("order_id"); param != "" {
+		orderIDs = strings.Split(param, ",")
+	}
+	productID := query.Get("product_id")
+	var fills []*fill
+	for _, f := range exchange.fills {
+		if productID != "" && f.ProductID != productID {
+			continue
+		}
+		if orderIDs != nil && !slices.Contains(orderIDs, f.OrderID.String()) {
+			continue
+		}
+		fills = append(fills, f)
+	}
+	paginate(w, r, exchange.config.PageLimit, fills, func(f *fill) int64 { return f.seq })
+}
+
+// getTrades handles GET /products/<product-id>/trades, which pages by trade ID.
+func (exchange *Exchange) getTrades(w http.ResponseWriter, r *http.Request, productID string) {
+	p, ok := exchange.products[productID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound")
+		return
+	}
+	paginate(w, r, exchange.config.PageLimit, p.trades, func(trade *gdax.Trade) int64 { return trade.TradeID })
+}
@@ -0,0 +1,121 @@
+package gdax
+
+// A productFilter restricts a handler to a set of products.
+// An empty productFilter matches every product.
+type productFilter map[string]bool
+
+// A Handlers dispatches Messages to strongly typed handlers.
+// Its Handle method can be passed directly to Feed.
+type Handlers struct {
+	heartbeat []func(Message)
+	ticker    []func(Message)
+	l2update  []func(Message)
+	snapshot  []func(Message)
+	match     []func(Message)
+	errs      []func(Message)
+	unknown   []func(Message)
+}
+
+// NewHandlers creates a new, empty Handlers.
+func NewHandlers() *Handlers {
+	return &Handlers{}
+}
+
+// newProductFilter creates a productFilter for the specified productIDs.
+func newProductFilter(productIDs []string) productFilter {
+	filter := make(productFilter, len(productIDs))
+	for _, productID := range productIDs {
+		filter[productID] = true
+	}
+	return filter
+}
+
+// matches determines if a message passes the filter.
+// Messages without a product (e.g., Errors) always pass.
+func (filter productFilter) matches(m Message) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	p, ok := m.(interface{ productID() string })
+	if !ok || p.productID() == "" {
+		return true
+	}
+	return filter[p.productID()]
+}
+
+// filtered wraps a handler so that it is only called for the specified productIDs.
+func filtered(productIDs []string, f func(Message)) func(Message) {
+	filter := newProductFilter(productIDs)
+	return func(m Message) {
+		if filter.matches(m) {
+			f(m)
+		}
+	}
+}
+
+// OnHeartbeat registers a handler for Heartbeats of the specified productIDs (or all products if none are specified).
+func (h *Handlers) OnHeartbeat(f func(Heartbeat), productIDs ...string) *Handlers {
+	h.heartbeat = append(h.heartbeat, filtered(productIDs, func(m Message) { f(m.(Heartbeat)) }))
+	return h
+}
+
+// OnTicker registers a handler for Tickers of the specified productIDs (or all products if none are specified).
+func (h *Handlers) OnTicker(f func(Ticker), productIDs ...string) *Handlers {
+	h.ticker = append(h.ticker, filtered(productIDs, func(m Message) { f(m.(Ticker)) }))
+	return h
+}
+
+// OnL2Update registers a handler for L2Updates of the specified productIDs (or all products if none are specified).
+func (h *Handlers) OnL2Update(f func(L2Update), productIDs ...string) *Handlers {
+	h.l2update = append(h.l2update, filtered(productIDs, func(m Message) { f(m.(L2Update)) }))
+	return h
+}
+
+// OnSnapshot registers a handler for Snapshots of the specified productIDs (or all products if none are specified).
+func (h *Handlers) OnSnapshot(f func(Snapshot), productIDs ...string) *Handlers {
+	h.snapshot = append(h.snapshot, filtered(productIDs, func(m Message) { f(m.(Snapshot)) }))
+	return h
+}
+
+// OnMatch registers a handler for Matches of the specified productIDs (or all products if none are specified).
+func (h *Handlers) OnMatch(f func(Match), productIDs ...string) *Handlers {
+	h.match = append(h.match, filtered(productIDs, func(m Message) { f(m.(Match)) }))
+	return h
+}
+
+// OnError registers a handler for Errors.
+func (h *Handlers) OnError(f func(Error)) *Handlers {
+	h.errs = append(h.errs, func(m Message) { f(m.(Error)) })
+	return h
+}
+
+// OnUnknown registers a handler for messages of types that this package does not model.
+// The raw JSON of the message is available in Unknown.Raw.
+func (h *Handlers) OnUnknown(f func(Unknown), productIDs ...string) *Handlers {
+	h.unknown = append(h.unknown, filtered(productIDs, func(m Message) { f(m.(Unknown)) }))
+	return h
+}
+
+// Handle dispatches a message to the handlers registered for its type.
+func (h *Handlers) Handle(m Message) {
+	var handlers []func(Message)
+	switch m.(type) {
+	case Heartbeat:
+		handlers = h.heartbeat
+	case Ticker:
+		handlers = h.ticker
+	case L2Update:
+		handlers = h.l2update
+	case Snapshot:
+		handlers = h.snapshot
+	case Match:
+		handlers = h.match
+	case Error:
+		handlers = h.errs
+	case Unknown:
+		handlers = h.unknown
+	}
+	for _, handler := range handlers {
+		handler(m)
+	}
+}
@@ -0,0 +1,73 @@
+package gdax_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	tickerJSON = `
+		{
+		    "type": "ticker",
+		    "trade_id": 20153558,
+		    "sequence": 3262786978,
+		    "time": "2017-09-02T17:05:49.250000Z",
+		    "product_id": "%s",
+		    "price": "4388.01000000",
+		    "side": "buy",
+		    "last_size": "0.03000000",
+		    "best_bid": "4388",
+		    "best_ask": "4388.01"
+		}
+	`
+	matchJSON = `
+		{
+		    "type": "match",
+		    "trade_id": 10,
+		    "sequence": 50,
+		    "maker_order_id": "ac928c66-ca53-498f-9c13-a110027a60e8",
+		    "taker_order_id": "132fb6ae-456b-4654-b4e0-d681ac05cea1",
+		    "time": "2014-11-07T08:19:27.028459Z",
+		    "product_id": "BTC-USD",
+		    "size": "5.23512",
+		    "price": "400.23",
+		    "side": "sell"
+		}
+	`
+)
+
+func TestHandlers(t *testing.T) {
+	assert := assert.New(t)
+
+	var btcTicker, ethTicker gdax.Ticker
+	assert.NoError(json.Unmarshal([]byte(fmt.Sprintf(tickerJSON, "BTC-USD")), &btcTicker))
+	assert.NoError(json.Unmarshal([]byte(fmt.Sprintf(tickerJSON, "ETH-USD")), &ethTicker))
+	var match gdax.Match
+	assert.NoError(json.Unmarshal([]byte(matchJSON), &match))
+
+	var (
+		allTickers []string
+		btcTickers []string
+		matches    []int64
+		unknowns   []string
+	)
+	handlers := gdax.NewHandlers().
+		OnTicker(func(ticker gdax.Ticker) { allTickers = append(allTickers, ticker.ProductID) }).
+		OnTicker(func(ticker gdax.Ticker) { btcTickers = append(btcTickers, ticker.ProductID) }, "BTC-USD").
+		OnMatch(func(match gdax.Match) { matches = append(matches, match.TradeID) }).
+		OnUnknown(func(unknown gdax.Unknown) { unknowns = append(unknowns, string(unknown.Raw)) })
+
+	for _, m := range []gdax.Message{btcTicker, ethTicker, match, gdax.Unknown{Raw: json.RawMessage(`{"type":"done"}`)}} {
+		handlers.Handle(m)
+	}
+
+	assert.Equal(allTickers, []string{"BTC-USD", "ETH-USD"})
+	assert.Equal(btcTickers, []string{"BTC-USD"})
+	assert.Equal(matches, []int64{10})
+	assert.Equal(unknowns, []string{`{"type":"done"}`})
+	assert.Equal(btcTicker.MessageType(), gdax.TickerType)
+}
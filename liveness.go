@@ -0,0 +1,216 @@
+package gdax
+
+import (
+	"sync"
+	"time"
+)
+
+// Liveness Events
+const (
+	Stale       = "stale"
+	TradeGap    = "trade_gap"
+	SequenceGap = "sequence_gap"
+	Reconnected = "reconnected"
+	Rebalanced  = "rebalanced"
+)
+
+// A LivenessEvent is reported by a FeedStream when a product goes stale, a gap is detected, or the stream reconnects.
+// A ShardedFeedStream also reports a Rebalanced event for every product that is moved off of a dropped connection.
+type LivenessEvent struct {
+	Kind      string
+	ProductID string
+	Time      time.Time
+	// LastSeen is the last time a message was received for the product.
+	LastSeen time.Time
+	// Expected and Actual are the expected and received trade ID (TradeGap) or sequence (SequenceGap).
+	// Every ID strictly between Expected-1 and Actual was missed.
+	Expected int64
+	Actual   int64
+}
+
+// A ProductStatus is the liveness state of a single product of a FeedStream.
+type ProductStatus struct {
+	ProductID   string
+	LastSeen    time.Time
+	LastTradeID int64
+	Sequence    int64
+	Stale       bool
+}
+
+// A livenessMonitor tracks the liveness state of the products of a subscription.
+type livenessMonitor struct {
+	mu             sync.Mutex
+	products       map[string]*ProductStatus
+	staleAfter     time.Duration
+	checkTrades    bool
+	checkSequences bool
+	onEvent        func(LivenessEvent)
+}
+
+// newLivenessMonitor creates a livenessMonitor for the products of the specified subscription.
+// Trade gaps are only checked when subscribed to matches, and sequence gaps are only checked when subscribed to full,
+// since the sequence of every other channel skips the messages of the channels that are not subscribed to.
+func newLivenessMonitor(s *Subscription, staleAfter time.Duration, onEvent func(LivenessEvent)) *livenessMonitor {
+	monitor := livenessMonitor{
+		products:   make(map[string]*ProductStatus, len(s.ProductIDs)),
+		staleAfter: staleAfter,
+		onEvent:    onEvent,
+	}
+	for _, channel := range s.Channels {
+		switch channel {
+		case MatchesType:
+			monitor.checkTrades = true
+		case FullType:
+			monitor.checkTrades = true
+			monitor.checkSequences = true
+		}
+	}
+	now := time.Now()
+	for _, productID := range s.ProductIDs {
+		monitor.products[productID] = &ProductStatus{ProductID: productID, LastSeen: now}
+	}
+	return &monitor
+}
+
+// observe updates the liveness state of the product of the specified message.
+func (monitor *livenessMonitor) observe(m Message, receivedAt time.Time) {
+	var (
+		productID string
+		tradeID   int64
+		sequence  int64
+		isMatch   bool
+	)
+	switch m := m.(type) {
+	case Heartbeat:
+		productID, sequence = m.ProductID, m.Sequence
+		// a heartbeat only reports trades that were already sent.
+		tradeID = m.LastTradeID
+	case Ticker:
+		productID, sequence = m.ProductID, m.Sequence
+	case Match:
+		productID, tradeID, sequence, isMatch = m.ProductID, m.TradeID, m.Sequence, true
+	case Unknown:
+		productID, sequence = m.ProductID, m.Sequence
+	case L2Update:
+		productID = m.ProductID
+	case Snapshot:
+		productID = m.ProductID
+	}
+	if productID == "" {
+		return
+	}
+
+	var events []LivenessEvent
+	monitor.mu.Lock()
+	status, ok := monitor.products[productID]
+	if !ok {
+		status = &ProductStatus{ProductID: productID}
+		monitor.products[productID] = status
+	}
+	status.LastSeen = receivedAt
+	status.Stale = false
+	if monitor.checkTrades && tradeID > 0 {
+		if status.LastTradeID > 0 && ((isMatch && tradeID > status.LastTradeID+1) || (!isMatch && tradeID > status.LastTradeID)) {
+			events = append(events, monitor.event(TradeGap, status, receivedAt, status.LastTradeID+1, tradeID))
+		}
+		if tradeID > status.LastTradeID {
+			status.LastTradeID = tradeID
+		}
+	} else if tradeID > status.LastTradeID {
+		status.LastTradeID = tradeID
+	}
+	if sequence > 0 {
+		if monitor.checkSequences && status.Sequence > 0 && sequence > status.Sequence+1 {
+			events = append(events, monitor.event(SequenceGap, status, receivedAt, status.Sequence+1, sequence))
+		}
+		if sequence > status.Sequence {
+			status.Sequence = sequence
+		}
+	}
+	monitor.mu.Unlock()
+	monitor.report(events)
+}
+
+// checkStale marks products that have not been seen for too long as stale.
+// This function returns true if any product went stale since the last check.
+func (monitor *livenessMonitor) checkStale(now time.Time) bool {
+	if monitor.staleAfter <= 0 {
+		return false
+	}
+	var events []LivenessEvent
+	monitor.mu.Lock()
+	for _, status := range monitor.products {
+		if !status.Stale && now.Sub(status.LastSeen) >= monitor.staleAfter {
+			status.Stale = true
+			events = append(events, monitor.event(Stale, status, now, 0, 0))
+		}
+	}
+	monitor.mu.Unlock()
+	monitor.report(events)
+	return len(events) > 0
+}
+
+// add starts tracking the specified products, if they are not already tracked.
+func (monitor *livenessMonitor) add(productIDs []string, now time.Time) {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+	for _, productID := range productIDs {
+		if _, ok := monitor.products[productID]; !ok {
+			monitor.products[productID] = &ProductStatus{ProductID: productID, LastSeen: now}
+		}
+	}
+}
+
+// remove stops tracking the specified products.
+func (monitor *livenessMonitor) remove(productIDs []string) {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+	for _, productID := range productIDs {
+		delete(monitor.products, productID)
+	}
+}
+
+// reconnected resets the last seen time of every product after a reconnect.
+// Trade IDs and sequences are kept so that anything missed while disconnected is reported as a gap.
+func (monitor *livenessMonitor) reconnected(now time.Time) {
+	monitor.mu.Lock()
+	for _, status := range monitor.products {
+		status.LastSeen = now
+		status.Stale = false
+	}
+	monitor.mu.Unlock()
+	monitor.report([]LivenessEvent{{Kind: Reconnected, Time: now}})
+}
+
+// status gets the liveness state of the specified product.
+func (monitor *livenessMonitor) status(productID string) (ProductStatus, bool) {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+	status, ok := monitor.products[productID]
+	if !ok {
+		return ProductStatus{}, false
+	}
+	return *status, true
+}
+
+// event creates a LivenessEvent for the specified product.
+func (monitor *livenessMonitor) event(kind string, status *ProductStatus, now time.Time, expected, actual int64) LivenessEvent {
+	return LivenessEvent{
+		Kind:      kind,
+		ProductID: status.ProductID,
+		Time:      now,
+		LastSeen:  status.LastSeen,
+		Expected:  expected,
+		Actual:    actual,
+	}
+}
+
+// report sends events to the event handler, if any.
+func (monitor *livenessMonitor) report(events []LivenessEvent) {
+	if monitor.onEvent == nil {
+		return
+	}
+	for _, event := range events {
+		monitor.onEvent(event)
+	}
+}
@@ -0,0 +1,95 @@
+package gdax
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// A LogLevel is the severity of a log entry. The levels have the same values as the slog levels.
+type LogLevel int
+
+// Log Levels
+const (
+	LevelDebug LogLevel = -4
+	LevelInfo  LogLevel = 0
+	LevelWarn  LogLevel = 4
+	LevelError LogLevel = 8
+)
+
+// redacted replaces the values of credentials in log entries.
+const redacted = "[REDACTED]"
+
+// credentialHeaders are the headers that are always redacted from log entries.
+var credentialHeaders = []string{
+	"CB-ACCESS-KEY",
+	"CB-ACCESS-SIGN",
+	"CB-ACCESS-PASSPHRASE",
+	"Authorization",
+	"Cookie",
+}
+
+// A Logger receives structured log entries about the requests made by an AccessInfo.
+// The arguments are alternating keys and values, as in slog.
+type Logger interface {
+	Enabled(ctx context.Context, level LogLevel) bool
+	Log(ctx context.Context, level LogLevel, msg string, args ...any)
+}
+
+// A nopLogger discards every log entry. It is the default Logger.
+type nopLogger struct{}
+
+// Enabled always returns false.
+func (nopLogger) Enabled(context.Context, LogLevel) bool {
+	return false
+}
+
+// Log does nothing.
+func (nopLogger) Log(context.Context, LogLevel, string, ...any) {}
+
+// A slogLogger adapts a slog.Logger to a Logger.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger creates a Logger that writes to the specified slog.Logger.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return slogLogger{logger: logger}
+}
+
+// Enabled determines if the slog.Logger handles entries of the specified level.
+func (l slogLogger) Enabled(ctx context.Context, level LogLevel) bool {
+	return l.logger.Enabled(ctx, slog.Level(level))
+}
+
+// Log writes an entry to the slog.Logger.
+func (l slogLogger) Log(ctx context.Context, level LogLevel, msg string, args ...any) {
+	l.logger.Log(ctx, slog.Level(level), msg, args...)
+}
+
+// redactHeaders copies headers, replacing the values of credential headers.
+func redactHeaders(header http.Header) http.Header {
+	clean := header.Clone()
+	for _, key := range credentialHeaders {
+		if _, ok := clean[http.CanonicalHeaderKey(key)]; ok {
+			clean.Set(key, redacted)
+		}
+	}
+	return clean
+}
+
+// logger gets the Logger of an AccessInfo, which is silent if none was specified.
+func (accessInfo *AccessInfo) logger() Logger {
+	if accessInfo.log == nil {
+		return nopLogger{}
+	}
+	return accessInfo.log
+}
+
+// logBody gets a body as it should appear in log entries.
+func (accessInfo *AccessInfo) logBody(body string) string {
+	if accessInfo.redactBodies && body != "" {
+		return redacted
+	}
+	return body
+}
@@ -65,18 +65,10 @@ type Order struct {
 }
 
 // An OrderCollection is an iterator of Orders.
-type OrderCollection struct {
-	pageableCollection
-	statuses  []string
-	productID string
-}
+type OrderCollection = Iterator[*Order]
 
 // An UUIDCollection is an iterator of UUIDs.
-type UUIDCollection struct {
-	pageableCollection
-	productID string
-	orderID   *uuid.UUID
-}
+type UUIDCollection = Iterator[*uuid.UUID]
 
 // PlaceMarketOrder places a market order.
 func (accessInfo *AccessInfo) PlaceMarketOrder(order *Order) (*Order, error) {
@@ -139,11 +131,7 @@ func (accessInfo *AccessInfo) PlaceLimitOrder(order *Order) (*Order, error) {
 // CancelOrder cancels an order with the specified orderID.
 // Note that this function is lazy.
 func (accessInfo *AccessInfo) CancelOrder(orderID *uuid.UUID) *UUIDCollection {
-	uuidCollection := UUIDCollection{
-		pageableCollection: accessInfo.newPageableCollection(false),
-		orderID:            orderID,
-	}
-	return &uuidCollection
+	return accessInfo.cancelOrders("", orderID)
 }
 
 // CancelAllOrders cancels all orders.
@@ -155,11 +143,24 @@ func (accessInfo *AccessInfo) CancelAllOrders() *UUIDCollection {
 // CancelAllOrdersForProduct cancels all orders with the specified productID.
 // Note that this function is lazy.
 func (accessInfo *AccessInfo) CancelAllOrdersForProduct(productID string) *UUIDCollection {
-	uuidCollection := UUIDCollection{
-		pageableCollection: accessInfo.newPageableCollection(false),
-		productID:          productID,
+	return accessInfo.cancelOrders(productID, nil)
+}
+
+// cancelOrders cancels all orders with the specified productID and orderID.
+func (accessInfo *AccessInfo) cancelOrders(productID string, orderID *uuid.UUID) *UUIDCollection {
+	// DELETE /orders
+	var (
+		productIDParam string
+		orderIDParam   string
+	)
+	if productID != "" {
+		productIDParam = "product_id=" + productID
 	}
-	return &uuidCollection
+	if orderID != nil {
+		orderIDParam = "order_id=" + orderID.String()
+	}
+	params := strings.Join(stringFilter([]string{productIDParam, orderIDParam}, notEmpty), "&")
+	return newIterator[*uuid.UUID](accessInfo, http.MethodDelete, "/orders", params, "", false)
 }
 
 // GetOrder gets the order with the specified orderID.
@@ -185,58 +186,20 @@ func (accessInfo *AccessInfo) GetOrdersForProduct(productID string, statuses ...
 	if len(statuses) == 0 {
 		updatedStatuses = append(updatedStatuses, All)
 	}
-	orderCollection := OrderCollection{
-		pageableCollection: accessInfo.newPageableCollection(true),
-		statuses:           updatedStatuses,
-		productID:          productID,
-	}
-	return &orderCollection
-}
-
-// HasNext determines if there is another Order in this iterator.
-func (c *OrderCollection) HasNext() bool {
 	// GET /orders
-	var orders []Order
-	statusParams := strings.Join(stringMap(c.statuses, func(s string) string { return "status=" + s }), "&")
+	statusParams := strings.Join(stringMap(updatedStatuses, func(s string) string { return "status=" + s }), "&")
 	productParams := ""
-	if c.productID != "" {
-		productParams = fmt.Sprintf("product_id=%s", c.productID)
-	}
-	return c.pageableCollection.hasNext(http.MethodGet, "/orders", strings.Join(stringFilter([]string{statusParams, productParams}, notEmpty), "&"), "", &orders)
-}
-
-// HasNext determines if there is another UUID in this iterator.
-func (c *UUIDCollection) HasNext() bool {
-	// DELETE /orders
-	var (
-		productIDParam string
-		orderIDParam   string
-		cancelledIDs   []uuid.UUID
-	)
-	if c.productID != "" {
-		productIDParam = "product_id=" + c.productID
-	}
-	if c.orderID != nil {
-		orderIDParam = "order_id=" + c.orderID.String()
+	if productID != "" {
+		productParams = fmt.Sprintf("product_id=%s", productID)
 	}
-	params := strings.Join(stringFilter([]string{productIDParam, orderIDParam}, notEmpty), "&")
-	return c.pageableCollection.hasNext(http.MethodDelete, "/orders", params, "", &cancelledIDs)
-}
-
-// Next gets the next Order from the iterator.
-func (c *OrderCollection) Next() (*Order, error) {
-	order, err := c.pageableCollection.next()
-	if err != nil {
-		return nil, err
-	}
-	return order.Addr().Interface().(*Order), nil
+	params := strings.Join(stringFilter([]string{statusParams, productParams}, notEmpty), "&")
+	return newIterator[*Order](accessInfo, http.MethodGet, "/orders", params, "", true)
 }
 
-// Next gets the next UUID from the iterator.
-func (c *UUIDCollection) Next() (*uuid.UUID, error) {
-	id, err := c.pageableCollection.next()
-	if err != nil {
-		return nil, err
+// timestamp gets the creation time of an Order.
+func (order *Order) timestamp() time.Time {
+	if order.CreatedAt == nil {
+		return time.Time{}
 	}
-	return id.Addr().Interface().(*uuid.UUID), nil
+	return *order.CreatedAt
 }
@@ -0,0 +1,619 @@
+package gdax
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// paperEpsilon is the smallest size or amount that a PaperTrader does not treat as zero.
+const paperEpsilon = 1e-9
+
+// A Trader places orders and tracks them, their fills, and the balances they change.
+// AccessInfo trades on the exchange and PaperTrader simulates trading, so a strategy written against a Trader can
+// run against either.
+type Trader interface {
+	PlaceLimitOrder(order *Order) (*Order, error)
+	PlaceMarketOrder(order *Order) (*Order, error)
+	CancelOrder(orderID *uuid.UUID) *UUIDCollection
+	GetOrder(orderID *uuid.UUID) (*Order, error)
+	GetOrders(statuses ...string) *OrderCollection
+	GetFills(orderIDs ...*uuid.UUID) *FillCollection
+	GetAccounts() *AccountCollection
+}
+
+// PaperTraderOptions configure a PaperTrader.
+type PaperTraderOptions struct {
+	// Balances are the starting balances by currency (e.g., {"USD": 1000}).
+	Balances map[string]float64
+	// MakerFeeRate and TakerFeeRate are the fees charged in the quote currency, as fractions of the executed value.
+	MakerFeeRate float64
+	TakerFeeRate float64
+	// Now is the clock that timestamps orders and the fills of orders that take liquidity. If nil, time.Now is used.
+	Now func() time.Time
+	// Latency is how long orders and cancellations take to reach the simulated exchange. Until an order arrives, it
+	// is pending; it is executed by the first call to Handle at or after its arrival, before the message is applied.
+	Latency time.Duration
+}
+
+// A paperOrder is a simulated order with its unfilled size and hold.
+type paperOrder struct {
+	order     Order
+	remaining float64 // the size that is not filled yet (infinite for buys by funds)
+	funds     float64 // the funds that are not spent yet (buys by funds)
+	hold      float64
+	held      *Account
+	arrival   time.Time // when a pending order reaches the simulated exchange
+	cancelAt  time.Time // when the cancellation of an order reaches the simulated exchange
+	queue     float64   // the size resting ahead of an open order at its price
+}
+
+// A PaperTrader simulates trading without risking funds. It keeps a local order book of every product from the
+// level2 channel and simulated balances and holds, and charges the configured maker and taker fees.
+//
+// Orders that cross the local book are filled as takers at the prices of the book. Limit orders that rest are filled
+// as makers, at their limit price, by the trades of the matches channel through their price, up to the size of each
+// trade. A resting order joins the back of the queue at its price, so trades at its price first fill the size that
+// was resting ahead of it; the queue also shrinks as level2 updates reduce the level. Simulated orders are not
+// visible to the market, so the book is not changed by them beyond the levels they take.
+//
+// Messages are added with Handle, e.g., from a FeedStream subscribed to the level2 and matches channels.
+// All of its methods are safe for concurrent use.
+type PaperTrader struct {
+	options PaperTraderOptions
+
+	mu         sync.Mutex
+	books      map[string]*orderBook
+	accounts   []*Account
+	currencies map[string]*Account
+	orders     []*paperOrder
+	fills      []*Fill
+	tradeID    int64
+	lastPrices map[string]float64
+
+	// synthesizeBooks quotes products without a level2 book at their last trade price with unlimited size,
+	// which lets backtests trade on trades or candles alone.
+	synthesizeBooks bool
+}
+
+// NewPaperTrader creates a PaperTrader with the specified starting balances and fees.
+func NewPaperTrader(options *PaperTraderOptions) *PaperTrader {
+	trader := PaperTrader{books: make(map[string]*orderBook), currencies: make(map[string]*Account), lastPrices: make(map[string]float64)}
+	if options != nil {
+		trader.options = *options
+	}
+	if trader.options.Now == nil {
+		trader.options.Now = time.Now
+	}
+	currencies := make([]string, 0, len(trader.options.Balances))
+	for currency := range trader.options.Balances {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		account := trader.account(currency)
+		account.Balance = trader.options.Balances[currency]
+		account.Available = account.Balance
+	}
+	return &trader
+}
+
+// Handle executes the pending orders and cancellations that have arrived, then updates the local order book with a
+// Snapshot or an L2Update or fills resting orders with a Match.
+// Every other message is ignored, so Handle can be passed directly to Feed.
+func (trader *PaperTrader) Handle(m Message) {
+	trader.mu.Lock()
+	defer trader.mu.Unlock()
+	trader.advance(trader.now())
+	switch m := m.(type) {
+	case Snapshot:
+		book, ok := trader.books[m.ProductID]
+		if !ok || book.synthetic {
+			book = newOrderBook()
+			trader.books[m.ProductID] = book
+		}
+		book.apply(m)
+		trader.shrinkQueues(m.ProductID, book)
+	case L2Update:
+		if book, ok := trader.books[m.ProductID]; ok && !book.synthetic {
+			book.apply(m)
+			trader.shrinkQueues(m.ProductID, book)
+		}
+	case Ticker:
+		trader.lastPrices[m.ProductID] = m.Price
+	case Match:
+		trader.lastPrices[m.ProductID] = m.Price
+		if book, ok := trader.books[m.ProductID]; trader.synthesizeBooks && (!ok || book.synthetic) {
+			trader.books[m.ProductID] = &orderBook{
+				bids:      map[float64]float64{m.Price: math.Inf(1)},
+				asks:      map[float64]float64{m.Price: math.Inf(1)},
+				synthetic: true,
+			}
+		}
+		trader.fillResting(m)
+	}
+}
+
+// PlaceLimitOrder places a simulated limit order.
+func (trader *PaperTrader) PlaceLimitOrder(order *Order) (*Order, error) {
+	order.Type = Limit
+	return trader.place(order)
+}
+
+// PlaceMarketOrder places a simulated market order, which is filled immediately against the local order book.
+func (trader *PaperTrader) PlaceMarketOrder(order *Order) (*Order, error) {
+	order.Type = Market
+	return trader.place(order)
+}
+
+// CancelOrder cancels the open (or pending) order with the specified orderID and releases its hold.
+// With Latency, the order can still be filled until the cancellation arrives.
+// Note that this function is lazy, as is AccessInfo.CancelOrder.
+func (trader *PaperTrader) CancelOrder(orderID *uuid.UUID) *UUIDCollection {
+	return newSliceIterator(func() ([]*uuid.UUID, error) {
+		trader.mu.Lock()
+		defer trader.mu.Unlock()
+		po, ok := trader.find(orderID)
+		if !ok || (po.order.Status != Open && po.order.Status != Pending) || !po.cancelAt.IsZero() {
+			return nil, errors.New("order not found")
+		}
+		if trader.options.Latency > 0 {
+			po.cancelAt = trader.now().Add(trader.options.Latency)
+		} else {
+			trader.finish(po)
+		}
+		return []*uuid.UUID{po.order.ID}, nil
+	})
+}
+
+// GetOrder gets the simulated order with the specified orderID.
+func (trader *PaperTrader) GetOrder(orderID *uuid.UUID) (*Order, error) {
+	trader.mu.Lock()
+	defer trader.mu.Unlock()
+	po, ok := trader.find(orderID)
+	if !ok {
+		return nil, errors.New("order not found")
+	}
+	order := po.order
+	return &order, nil
+}
+
+// GetOrders gets the simulated orders with the given statuses (or all orders, if none are given), newest first.
+func (trader *PaperTrader) GetOrders(statuses ...string) *OrderCollection {
+	return newSliceIterator(func() ([]*Order, error) {
+		trader.mu.Lock()
+		defer trader.mu.Unlock()
+		var orders []*Order
+		for idx := len(trader.orders) - 1; idx >= 0; idx-- {
+			order := trader.orders[idx].order
+			if len(statuses) == 0 || slices.Contains(statuses, All) || slices.Contains(statuses, order.Status) {
+				orders = append(orders, &order)
+			}
+		}
+		return orders, nil
+	})
+}
+
+// GetFills gets the simulated fills of the specified orders (or of all orders, if none are given), newest first.
+func (trader *PaperTrader) GetFills(orderIDs ...*uuid.UUID) *FillCollection {
+	return newSliceIterator(func() ([]*Fill, error) {
+		trader.mu.Lock()
+		defer trader.mu.Unlock()
+		var fills []*Fill
+		for idx := len(trader.fills) - 1; idx >= 0; idx-- {
+			fill := *trader.fills[idx]
+			if len(orderIDs) == 0 || containsUUID(orderIDs, fill.OrderID) {
+				fills = append(fills, &fill)
+			}
+		}
+		return fills, nil
+	})
+}
+
+// GetAccounts gets the simulated accounts.
+func (trader *PaperTrader) GetAccounts() *AccountCollection {
+	return newSliceIterator(func() ([]*Account, error) {
+		trader.mu.Lock()
+		defer trader.mu.Unlock()
+		accounts := make([]*Account, len(trader.accounts))
+		for idx, account := range trader.accounts {
+			copied := *account
+			accounts[idx] = &copied
+		}
+		return accounts, nil
+	})
+}
+
+// now gets the current time of the trader's clock.
+func (trader *PaperTrader) now() time.Time {
+	return trader.options.Now()
+}
+
+// account gets the simulated account of the specified currency, creating it if needed.
+func (trader *PaperTrader) account(currency string) *Account {
+	if account, ok := trader.currencies[currency]; ok {
+		return account
+	}
+	id := uuid.New()
+	account := &Account{ID: &id, Currency: currency}
+	trader.accounts = append(trader.accounts, account)
+	trader.currencies[currency] = account
+	return account
+}
+
+// find gets the simulated order with the specified orderID.
+func (trader *PaperTrader) find(orderID *uuid.UUID) (*paperOrder, bool) {
+	for _, po := range trader.orders {
+		if orderID != nil && *po.order.ID == *orderID {
+			return po, true
+		}
+	}
+	return nil, false
+}
+
+// validatePaperOrder checks an order and fills in the default time in force of limit orders.
+func validatePaperOrder(order *Order) error {
+	if order.Side != Buy && order.Side != Sell {
+		return fmt.Errorf("invalid side %q", order.Side)
+	}
+	if order.Type == Market {
+		if (order.Size > 0) == (order.Funds > 0) {
+			return errors.New("market orders require either size or funds")
+		}
+		if order.Funds > 0 && order.Side == Sell {
+			return errors.New("market sell orders require size")
+		}
+		return nil
+	}
+	if order.Price <= 0 || order.Size <= 0 {
+		return errors.New("limit orders require a positive price and size")
+	}
+	switch order.TimeInForce {
+	case "":
+		order.TimeInForce = GoodTillCancelled
+	case GoodTillCancelled, GoodTillTime, ImmediateOrCancel, FillOrKill:
+	default:
+		return fmt.Errorf("invalid time_in_force %q", order.TimeInForce)
+	}
+	return nil
+}
+
+// place holds the funds that an order needs and executes it, immediately or, with Latency, once it arrives.
+func (trader *PaperTrader) place(order *Order) (*Order, error) {
+	if order.ClientOid == nil {
+		clientOid := uuid.New()
+		order.ClientOid = &clientOid
+	}
+	if err := validatePaperOrder(order); err != nil {
+		return nil, err
+	}
+
+	trader.mu.Lock()
+	defer trader.mu.Unlock()
+	base, quote, ok := strings.Cut(order.ProductID, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid product %q", order.ProductID)
+	}
+	book, ok := trader.books[order.ProductID]
+	if !ok {
+		return nil, fmt.Errorf("no order book for %s; subscribe to its level2 channel", order.ProductID)
+	}
+
+	id := uuid.New()
+	createdAt := trader.now()
+	po := &paperOrder{order: *order, remaining: order.Size, funds: order.Funds}
+	po.order.ID = &id
+	po.order.CreatedAt = &createdAt
+	po.order.Status = Pending
+	po.order.FillFees, po.order.FilledSize, po.order.ExecutedValue, po.order.Settled = 0, 0, 0, false
+	if po.order.Funds > 0 {
+		po.remaining = math.Inf(1)
+	}
+	opposite := book.levels(oppositeSide(order.Side))
+	if order.PostOnly && len(opposite) > 0 && po.crosses(opposite[0].price) {
+		return nil, errors.New("post only order would take liquidity")
+	}
+
+	held, amount := trader.account(base), order.Size
+	if order.Side == Buy {
+		held = trader.account(quote)
+		switch {
+		case order.Funds > 0:
+			amount = order.Funds
+		case order.Type == Limit:
+			amount = order.Price * order.Size * (1 + trader.options.TakerFeeRate)
+		default:
+			amount = trader.cost(po, opposite)
+		}
+	}
+	if amount > held.Available+paperEpsilon {
+		return nil, errors.New("Insufficient funds")
+	}
+	po.hold, po.held = amount, held
+	held.Holds += amount
+	held.Available = held.Balance - held.Holds
+	trader.orders = append(trader.orders, po)
+
+	if trader.options.Latency > 0 {
+		po.arrival = createdAt.Add(trader.options.Latency)
+	} else {
+		trader.execute(po)
+	}
+	placed := po.order
+	return &placed, nil
+}
+
+// execute fills an order that has arrived against the local order book. If it is not filled, a good till cancelled
+// (or good till time) limit order rests; any other order is done. A post only order that would take liquidity is
+// cancelled. A market buy by size is held again at its cost in the current book, and is only filled as far as its
+// hold (and so the available balance) allows.
+func (trader *PaperTrader) execute(po *paperOrder) {
+	po.arrival = time.Time{}
+	book := trader.books[po.order.ProductID]
+	side := oppositeSide(po.order.Side)
+	opposite := book.levels(side)
+	if po.order.PostOnly && len(opposite) > 0 && po.crosses(opposite[0].price) {
+		trader.finish(po)
+		return
+	}
+	if po.order.Type == Market && po.order.Side == Buy && po.order.Funds == 0 {
+		trader.rehold(po, trader.cost(po, opposite))
+	}
+	if po.order.TimeInForce != FillOrKill || liquidity(po, opposite) >= po.order.Size-paperEpsilon {
+		trader.take(po, book, opposite)
+	}
+	resting := po.order.TimeInForce == GoodTillCancelled || po.order.TimeInForce == GoodTillTime
+	if po.order.Type == Limit && resting && po.remaining > paperEpsilon {
+		po.order.Status = Open
+		if !book.synthetic {
+			po.queue = book.side(po.order.Side)[po.order.Price]
+		}
+	} else {
+		trader.finish(po)
+	}
+}
+
+// advance executes the pending orders and then the cancellations that have arrived by the specified time.
+func (trader *PaperTrader) advance(now time.Time) {
+	for _, po := range trader.orders {
+		if po.order.Status == Pending && !po.arrival.IsZero() && !po.arrival.After(now) {
+			trader.execute(po)
+		}
+	}
+	for _, po := range trader.orders {
+		if !po.cancelAt.IsZero() && !po.cancelAt.After(now) {
+			po.cancelAt = time.Time{}
+			if po.order.Status != Done {
+				trader.finish(po)
+			}
+		}
+	}
+}
+
+// shrinkQueues limits the queue ahead of each open order of a product to the size of its level in the book,
+// since the orders ahead of it can only be cancelled or filled.
+func (trader *PaperTrader) shrinkQueues(productID string, book *orderBook) {
+	for _, po := range trader.orders {
+		if po.order.Status == Open && po.order.ProductID == productID {
+			po.queue = math.Min(po.queue, book.side(po.order.Side)[po.order.Price])
+		}
+	}
+}
+
+// oppositeSide gets the side that an order of the specified side matches against.
+func oppositeSide(side string) string {
+	if side == Buy {
+		return Sell
+	}
+	return Buy
+}
+
+// crosses determines if a simulated order can match against the specified price.
+func (po *paperOrder) crosses(price float64) bool {
+	if po.order.Type == Market {
+		return true
+	}
+	if po.order.Side == Buy {
+		return po.order.Price >= price
+	}
+	return po.order.Price <= price
+}
+
+// cost estimates the funds that a market buy by size needs from the levels of the asks.
+func (trader *PaperTrader) cost(po *paperOrder, asks []priceLevel) float64 {
+	var cost float64
+	remaining := po.order.Size
+	for _, level := range asks {
+		size := math.Min(remaining, level.size)
+		cost += size * level.price * (1 + trader.options.TakerFeeRate)
+		if remaining -= size; remaining <= paperEpsilon {
+			break
+		}
+	}
+	return cost
+}
+
+// liquidity gets the total size of the levels that a simulated order crosses.
+func liquidity(po *paperOrder, levels []priceLevel) float64 {
+	var size float64
+	for _, level := range levels {
+		if !po.crosses(level.price) {
+			break
+		}
+		size += level.size
+	}
+	return size
+}
+
+// take fills a simulated order as a taker against the levels of the book that it crosses, best price first,
+// removing the size it takes from the local book.
+func (trader *PaperTrader) take(po *paperOrder, book *orderBook, levels []priceLevel) {
+	for _, level := range levels {
+		if po.remaining <= paperEpsilon || !po.crosses(level.price) {
+			return
+		}
+		size := math.Min(po.remaining, level.size)
+		if po.funds > 0 {
+			size = math.Min(size, po.funds/(level.price*(1+trader.options.TakerFeeRate)))
+		}
+		// an order never spends more than it holds, even if the book moved since the hold was estimated.
+		if po.order.Side == Buy {
+			size = math.Min(size, po.hold/(level.price*(1+trader.options.TakerFeeRate)))
+		} else {
+			size = math.Min(size, po.hold)
+		}
+		if size <= paperEpsilon {
+			return
+		}
+		trader.fill(po, level.price, size, Taker, trader.now())
+		book.set(oppositeSide(po.order.Side), level.price, level.size-size)
+	}
+}
+
+// fillResting fills the resting orders of the product of a Match that it crosses at their limit price as makers, up
+// to the size of the trade. Like the exchange, orders at the best price are filled first, and orders at the same
+// price in the order they were placed. A trade at the price of an order first fills the queue ahead of it.
+func (trader *PaperTrader) fillResting(m Match) {
+	at := trader.now()
+	if m.Time != nil {
+		at = *m.Time
+	}
+	var resting []*paperOrder
+	for _, po := range trader.orders {
+		if po.order.Status == Open && po.order.ProductID == m.ProductID && po.crosses(m.Price) {
+			resting = append(resting, po)
+		}
+	}
+	// the best price is the highest bid or the lowest ask; the stable sort keeps the order of placement.
+	slices.SortStableFunc(resting, func(a, b *paperOrder) int {
+		if a.order.Side != b.order.Side {
+			return strings.Compare(a.order.Side, b.order.Side)
+		}
+		if a.order.Side == Buy {
+			return cmp.Compare(b.order.Price, a.order.Price)
+		}
+		return cmp.Compare(a.order.Price, b.order.Price)
+	})
+
+	volume := m.Size
+	for _, po := range resting {
+		if volume <= paperEpsilon {
+			return
+		}
+		available := volume
+		if m.Price == po.order.Price {
+			queued := math.Min(po.queue, available)
+			po.queue -= queued
+			available -= queued
+		}
+		size := math.Min(po.remaining, available)
+		if size <= paperEpsilon {
+			continue
+		}
+		trader.fill(po, po.order.Price, size, Maker, at)
+		volume -= size
+		if po.remaining <= paperEpsilon {
+			trader.finish(po)
+		}
+	}
+}
+
+// fill records a simulated fill and settles it, releasing the part of the order's hold that it used.
+func (trader *PaperTrader) fill(po *paperOrder, price, size float64, liquidity string, at time.Time) {
+	feeRate := trader.options.TakerFeeRate
+	if liquidity == Maker {
+		feeRate = trader.options.MakerFeeRate
+	}
+	value := size * price
+	fee := value * feeRate
+	po.remaining -= size
+	po.order.FilledSize += size
+	po.order.ExecutedValue += value
+	po.order.FillFees += fee
+	if po.funds > 0 {
+		po.funds -= value + fee
+	}
+
+	base, quote, _ := strings.Cut(po.order.ProductID, "-")
+	baseAccount, quoteAccount := trader.account(base), trader.account(quote)
+	if po.order.Side == Buy {
+		trader.release(po, value+fee)
+		baseAccount.Balance += size
+		quoteAccount.Balance -= value + fee
+	} else {
+		trader.release(po, size)
+		baseAccount.Balance -= size
+		quoteAccount.Balance += value - fee
+	}
+	baseAccount.Available = baseAccount.Balance - baseAccount.Holds
+	quoteAccount.Available = quoteAccount.Balance - quoteAccount.Holds
+
+	trader.tradeID++
+	trader.fills = append(trader.fills, &Fill{
+		TradeID:   trader.tradeID,
+		ProductID: po.order.ProductID,
+		Price:     price,
+		Size:      size,
+		OrderID:   po.order.ID,
+		CreatedAt: &at,
+		Liquidity: liquidity,
+		Fee:       fee,
+		Settled:   true,
+		Side:      po.order.Side,
+	})
+}
+
+// release releases up to the specified amount of the hold of a simulated order.
+func (trader *PaperTrader) release(po *paperOrder, amount float64) {
+	if po.held == nil {
+		return
+	}
+	amount = math.Min(amount, po.hold)
+	po.hold -= amount
+	po.held.Holds -= amount
+	if po.held.Holds < paperEpsilon {
+		po.held.Holds = 0
+	}
+	po.held.Available = po.held.Balance - po.held.Holds
+}
+
+// rehold changes the hold of a simulated order to the specified amount, as far as the available balance allows.
+// A market buy by size holds its estimated cost when it is placed; once it arrives, the book may have moved.
+func (trader *PaperTrader) rehold(po *paperOrder, amount float64) {
+	if po.held == nil {
+		return
+	}
+	if amount < po.hold {
+		trader.release(po, po.hold-amount)
+		return
+	}
+	extra := math.Max(math.Min(amount-po.hold, po.held.Available), 0)
+	po.hold += extra
+	po.held.Holds += extra
+	po.held.Available = po.held.Balance - po.held.Holds
+}
+
+// finish marks a simulated order as done and releases what is left of its hold.
+func (trader *PaperTrader) finish(po *paperOrder) {
+	po.order.Status = Done
+	po.order.Settled = true
+	trader.release(po, math.Inf(1))
+}
+
+// containsUUID determines if a slice contains the specified UUID.
+func containsUUID(ids []*uuid.UUID, id *uuid.UUID) bool {
+	for _, other := range ids {
+		if other != nil && id != nil && *other == *id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package gdax_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ gdax.Trader = (*gdax.AccessInfo)(nil)
+	_ gdax.Trader = (*gdax.PaperTrader)(nil)
+)
+
+// balances gets the balance, holds, and available balance of every account of a Trader by currency.
+func balances(t *testing.T, trader gdax.Trader) map[string][3]float64 {
+	accounts, err := trader.GetAccounts().Collect(context.Background())
+	assert.NoError(t, err)
+	balances := make(map[string][3]float64)
+	for _, account := range accounts {
+		balances[account.Currency] = [3]float64{account.Balance, account.Holds, account.Available}
+	}
+	return balances
+}
+
+func TestPaperTrader(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	trader := gdax.NewPaperTrader(&gdax.PaperTraderOptions{Balances: map[string]float64{"USD": 1000}, TakerFeeRate: 0.01})
+	var snapshot gdax.Snapshot
+	snapshot.Type = gdax.SnapshotType
+	snapshot.ProductID = "BTC-USD"
+	snapshot.Bids = []gdax.Bid{{Price: 90, Size: 1}}
+	snapshot.Asks = []gdax.Ask{{Price: 100, Size: 1}, {Price: 200, Size: 1}}
+	trader.Handle(snapshot)
+
+	// fills 1 BTC at 100 and rests the other 1 BTC at 150, holding 300 USD plus the taker fee.
+	order, err := trader.PlaceLimitOrder(&gdax.Order{Side: gdax.Buy, ProductID: "BTC-USD", Price: 150, Size: 2})
+	assert.NoError(err)
+	assert.Equal(order.Status, gdax.Open)
+	assert.Equal(order.FilledSize, 1.0)
+	assert.Equal(order.FillFees, 1.0)
+	assert.Equal(balances(t, trader)["USD"], [3]float64{899, 202, 697})
+
+	// a trade through the resting order fills it as a maker, without a fee.
+	trader.Handle(match(11, time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), 140, 0.5))
+	order, err = trader.GetOrder(order.ID)
+	assert.NoError(err)
+	assert.Equal(order.FilledSize, 1.5)
+	assert.Equal(balances(t, trader)["USD"], [3]float64{824, 127, 697})
+
+	canceled, err := trader.CancelOrder(order.ID).Collect(ctx)
+	assert.NoError(err)
+	assert.Equal(canceled, []*uuid.UUID{order.ID})
+	assert.Equal(balances(t, trader), map[string][3]float64{"USD": {824, 0, 824}, "BTC": {1.5, 0, 1.5}})
+
+	fills, err := trader.GetFills(order.ID).Collect(ctx)
+	assert.NoError(err)
+	assert.Len(fills, 2)
+	assert.Equal(fills[0].Liquidity, gdax.Maker)
+	assert.Equal(fills[0].Price, 150.0)
+	assert.Equal(fills[1].Liquidity, gdax.Taker)
+	assert.Equal(fills[1].Price, 100.0)
+
+	sold, err := trader.PlaceMarketOrder(&gdax.Order{Side: gdax.Sell, ProductID: "BTC-USD", Size: 1})
+	assert.NoError(err)
+	assert.Equal(sold.Status, gdax.Done)
+	assert.Equal(balances(t, trader)["USD"], [3]float64{824 + 90 - 0.9, 0, 824 + 90 - 0.9})
+
+	open, err := trader.GetOrders(gdax.Open).Collect(ctx)
+	assert.NoError(err)
+	assert.Empty(open)
+	orders, err := trader.GetOrders().Collect(ctx)
+	assert.NoError(err)
+	assert.Len(orders, 2)
+	assert.Equal(orders[0].ID, sold.ID)
+}
+
+func TestPaperTraderRejects(t *testing.T) {
+	assert := assert.New(t)
+
+	trader := gdax.NewPaperTrader(&gdax.PaperTraderOptions{Balances: map[string]float64{"USD": 100}})
+	var snapshot gdax.Snapshot
+	snapshot.Type = gdax.SnapshotType
+	snapshot.ProductID = "BTC-USD"
+	snapshot.Asks = []gdax.Ask{{Price: 100, Size: 1}}
+	trader.Handle(snapshot)
+
+	_, err := trader.PlaceLimitOrder(&gdax.Order{Side: gdax.Buy, ProductID: "ETH-USD", Price: 10, Size: 1})
+	assert.Error(err)
+	_, err = trader.PlaceLimitOrder(&gdax.Order{Side: gdax.Buy, ProductID: "BTC-USD", Price: 50, Size: 3})
+	assert.EqualError(err, "Insufficient funds")
+	_, err = trader.PlaceLimitOrder(&gdax.Order{Side: gdax.Buy, ProductID: "BTC-USD", Price: 100, Size: 1, PostOnly: true})
+	assert.Error(err)
+
+	// a fill or kill order is not filled unless the book has its full size.
+	order, err := trader.PlaceLimitOrder(&gdax.Order{Side: gdax.Buy, ProductID: "BTC-USD", Price: 50, Size: 1, TimeInForce: gdax.FillOrKill})
+	assert.NoError(err)
+	assert.Equal(order.Status, gdax.Done)
+	assert.Equal(order.FilledSize, 0.0)
+	assert.Equal(balances(t, trader)["USD"], [3]float64{100, 0, 100})
+}
+
+func TestPaperTraderLatencyBookMoves(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	trader := gdax.NewPaperTrader(&gdax.PaperTraderOptions{
+		Balances: map[string]float64{"USD": 100},
+		Now:      func() time.Time { return now },
+		Latency:  time.Second,
+	})
+	var snapshot gdax.Snapshot
+	snapshot.Type = gdax.SnapshotType
+	snapshot.ProductID = "BTC-USD"
+	snapshot.Asks = []gdax.Ask{{Price: 100, Size: 1}}
+	trader.Handle(snapshot)
+
+	// holds the cost of 1 BTC in the book when it is placed.
+	order, err := trader.PlaceMarketOrder(&gdax.Order{Side: gdax.Buy, ProductID: "BTC-USD", Size: 1})
+	assert.NoError(err)
+	assert.Equal(order.Status, gdax.Pending)
+	assert.Equal(balances(t, trader)["USD"], [3]float64{100, 100, 0})
+
+	// the ask moves before the order arrives, so it only buys what its hold can pay for.
+	now = now.Add(500 * time.Millisecond)
+	snapshot.Asks = []gdax.Ask{{Price: 200, Size: 1}}
+	trader.Handle(snapshot)
+	now = now.Add(time.Second)
+	trader.Handle(snapshot)
+	order, err = trader.GetOrder(order.ID)
+	assert.NoError(err)
+	assert.Equal(order.Status, gdax.Done)
+	assert.InDelta(order.FilledSize, 0.5, 1e-9)
+	usd := balances(t, trader)["USD"]
+	assert.InDelta(usd[0], 0, 1e-9)
+	assert.Equal(usd[1], 0.0)
+	assert.GreaterOrEqual(usd[2], -1e-9)
+
+	// with enough available, the order is held again at its cost in the current book and fills in full.
+	trader = gdax.NewPaperTrader(&gdax.PaperTraderOptions{
+		Balances: map[string]float64{"USD": 300},
+		Now:      func() time.Time { return now },
+		Latency:  time.Second,
+	})
+	snapshot.Asks = []gdax.Ask{{Price: 100, Size: 1}}
+	trader.Handle(snapshot)
+	order, err = trader.PlaceMarketOrder(&gdax.Order{Side: gdax.Buy, ProductID: "BTC-USD", Size: 1})
+	assert.NoError(err)
+	snapshot.Asks = []gdax.Ask{{Price: 200, Size: 1}}
+	trader.Handle(snapshot)
+	now = now.Add(time.Second)
+	trader.Handle(snapshot)
+	order, err = trader.GetOrder(order.ID)
+	assert.NoError(err)
+	assert.Equal(order.FilledSize, 1.0)
+	assert.Equal(balances(t, trader)["USD"], [3]float64{100, 0, 100})
+}
+
+func TestPaperTraderPricePriority(t *testing.T) {
+	assert := assert.New(t)
+
+	trader := gdax.NewPaperTrader(&gdax.PaperTraderOptions{Balances: map[string]float64{"USD": 1000}})
+	var snapshot gdax.Snapshot
+	snapshot.Type = gdax.SnapshotType
+	snapshot.ProductID = "BTC-USD"
+	snapshot.Asks = []gdax.Ask{{Price: 200, Size: 1}}
+	trader.Handle(snapshot)
+
+	older, err := trader.PlaceLimitOrder(&gdax.Order{Side: gdax.Buy, ProductID: "BTC-USD", Price: 90, Size: 1})
+	assert.NoError(err)
+	newer, err := trader.PlaceLimitOrder(&gdax.Order{Side: gdax.Buy, ProductID: "BTC-USD", Price: 95, Size: 1})
+	assert.NoError(err)
+
+	// a trade through both orders fills the better priced one first, even though it was placed later.
+	trader.Handle(match(1, time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), 90, 1))
+	newer, err = trader.GetOrder(newer.ID)
+	assert.NoError(err)
+	assert.Equal(newer.Status, gdax.Done)
+	assert.Equal(newer.FilledSize, 1.0)
+	older, err = trader.GetOrder(older.ID)
+	assert.NoError(err)
+	assert.Equal(older.Status, gdax.Open)
+	assert.Equal(older.FilledSize, 0.0)
+
+	trader.Handle(match(2, time.Date(2018, 1, 1, 0, 0, 1, 0, time.UTC), 90, 1))
+	older, err = trader.GetOrder(older.ID)
+	assert.NoError(err)
+	assert.Equal(older.FilledSize, 1.0)
+	assert.Equal(balances(t, trader)["USD"], [3]float64{815, 0, 815})
+}
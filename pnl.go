@@ -0,0 +1,280 @@
+package gdax
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// A LotMethod chooses the open lots that a fill closes.
+type LotMethod int
+
+const (
+	// FIFO closes the oldest lots first.
+	FIFO LotMethod = iota
+	// LIFO closes the newest lots first.
+	LIFO
+	// AverageCost pools the open lots into a single lot at their average price.
+	AverageCost
+)
+
+// String gets the name of the method.
+func (method LotMethod) String() string {
+	switch method {
+	case FIFO:
+		return "FIFO"
+	case LIFO:
+		return "LIFO"
+	case AverageCost:
+		return "AverageCost"
+	}
+	return fmt.Sprintf("LotMethod(%d)", int(method))
+}
+
+// pnlEpsilon is the size below which a lot is considered closed.
+const pnlEpsilon = 1e-12
+
+// A Lot is an open position in the base currency of a product, opened by one or more fills.
+// A long lot (Side is Buy) was bought and is closed by selling; a short lot (Side is Sell) was sold and is closed by
+// buying.
+type Lot struct {
+	ProductID string
+	Side      string
+	// Opened is the time of the first fill of the lot, and TradeID is its trade.
+	Opened  time.Time
+	TradeID int64
+	// Size is the open size of the lot in the base currency.
+	Size float64
+	// Price is the price per unit of the base currency in the quote currency, including the fees of the fills that
+	// opened the lot: the cost of a long lot and the proceeds of a short lot.
+	Price float64
+}
+
+// Unrealized gets the profit of closing the lot at the mark price, without fees.
+func (lot Lot) Unrealized(mark float64) float64 {
+	if lot.Side == Sell {
+		return (lot.Price - mark) * lot.Size
+	}
+	return (mark - lot.Price) * lot.Size
+}
+
+// A Disposal is the part of a lot that was closed by a fill.
+type Disposal struct {
+	ProductID string
+	// Side is the side of the lot that was closed.
+	Side string
+	// Opened and Closed are the times of the fills that opened and closed the lot.
+	Opened time.Time
+	Closed time.Time
+	Size   float64
+	// Cost is what was paid for the size, and Proceeds are what it was sold for, in the quote currency and after fees.
+	Cost     float64
+	Proceeds float64
+}
+
+// Realized gets the profit of the disposal.
+func (disposal Disposal) Realized() float64 {
+	return disposal.Proceeds - disposal.Cost
+}
+
+// A Position accounts for the fills of one product.
+type Position struct {
+	ProductID string
+	Method    LotMethod
+	// Lots are the open lots, oldest first. They are all on the same side.
+	Lots []Lot
+	// Disposals are the closed parts of lots, in the order they were closed.
+	Disposals []Disposal
+	// Realized is the profit of the Disposals, and Fees are the fees of all fills, in the quote currency.
+	Realized float64
+	Fees     float64
+}
+
+// Size gets the open size of the position: positive if long and negative if short.
+func (position *Position) Size() float64 {
+	var size float64
+	for _, lot := range position.Lots {
+		if lot.Side == Sell {
+			size -= lot.Size
+		} else {
+			size += lot.Size
+		}
+	}
+	return size
+}
+
+// AveragePrice gets the average price of the open lots, or zero if the position is closed.
+func (position *Position) AveragePrice() float64 {
+	var size, value float64
+	for _, lot := range position.Lots {
+		size += lot.Size
+		value += lot.Size * lot.Price
+	}
+	if size == 0 {
+		return 0
+	}
+	return value / size
+}
+
+// Unrealized gets the profit of closing all open lots at the mark price, without fees.
+func (position *Position) Unrealized(mark float64) float64 {
+	var unrealized float64
+	for _, lot := range position.Lots {
+		unrealized += lot.Unrealized(mark)
+	}
+	return unrealized
+}
+
+// add accounts for a fill: it closes open lots on the other side with the lot method, then opens a lot with the rest.
+func (position *Position) add(fill *Fill) {
+	var at time.Time
+	if fill.CreatedAt != nil {
+		at = *fill.CreatedAt
+	}
+	position.Fees += fill.Fee
+	// price is the price per unit after fees: what is paid per unit bought, or received per unit sold.
+	price := fill.Price + fill.Fee/fill.Size
+	if fill.Side == Sell {
+		price = fill.Price - fill.Fee/fill.Size
+	}
+
+	remaining := fill.Size
+	for remaining > pnlEpsilon && len(position.Lots) > 0 && position.Lots[0].Side != fill.Side {
+		idx := 0
+		if position.Method == LIFO {
+			idx = len(position.Lots) - 1
+		}
+		lot := &position.Lots[idx]
+		size := math.Min(remaining, lot.Size)
+		disposal := Disposal{ProductID: position.ProductID, Side: lot.Side, Opened: lot.Opened, Closed: at, Size: size}
+		if lot.Side == Sell {
+			disposal.Cost, disposal.Proceeds = size*price, size*lot.Price
+		} else {
+			disposal.Cost, disposal.Proceeds = size*lot.Price, size*price
+		}
+		position.Disposals = append(position.Disposals, disposal)
+		position.Realized += disposal.Realized()
+		remaining -= size
+		lot.Size -= size
+		if lot.Size <= pnlEpsilon {
+			position.Lots = append(position.Lots[:idx], position.Lots[idx+1:]...)
+		}
+	}
+	if remaining <= pnlEpsilon {
+		return
+	}
+
+	if position.Method == AverageCost && len(position.Lots) > 0 {
+		lot := &position.Lots[0]
+		lot.Price = (lot.Price*lot.Size + price*remaining) / (lot.Size + remaining)
+		lot.Size += remaining
+		return
+	}
+	position.Lots = append(position.Lots, Lot{
+		ProductID: position.ProductID,
+		Side:      fill.Side,
+		Opened:    at,
+		TradeID:   fill.TradeID,
+		Size:      remaining,
+		Price:     price,
+	})
+}
+
+// PnL accounts for fills by product, matching them into lots with a lot method.
+// Fills must be added in the order they occurred. PnL is not safe for concurrent use.
+type PnL struct {
+	method    LotMethod
+	positions map[string]*Position
+}
+
+// NewPnL creates a PnL without any fills that matches lots with the specified method.
+func NewPnL(method LotMethod) *PnL {
+	return &PnL{method: method, positions: make(map[string]*Position)}
+}
+
+// Add accounts for a fill, which must have occurred after the fills already added.
+func (pnl *PnL) Add(fill *Fill) error {
+	if fill.Side != Buy && fill.Side != Sell {
+		return fmt.Errorf("fill %d has an unknown side %q", fill.TradeID, fill.Side)
+	}
+	if fill.Size <= 0 {
+		return fmt.Errorf("fill %d has a non-positive size %v", fill.TradeID, fill.Size)
+	}
+	pnl.Position(fill.ProductID).add(fill)
+	return nil
+}
+
+// AddAll accounts for all fills of a collection (e.g., from GetFills, which are newest first), oldest first.
+func (pnl *PnL) AddAll(ctx context.Context, fills *FillCollection) error {
+	collected, err := fills.Collect(ctx)
+	if err != nil {
+		return err
+	}
+	sortFills(collected)
+	for _, fill := range collected {
+		if err := pnl.Add(fill); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Position gets the position of a product.
+func (pnl *PnL) Position(productID string) *Position {
+	position, ok := pnl.positions[productID]
+	if !ok {
+		position = &Position{ProductID: productID, Method: pnl.method}
+		pnl.positions[productID] = position
+	}
+	return position
+}
+
+// A ProductPnL summarizes the position of a product.
+type ProductPnL struct {
+	ProductID string
+	// Size is the open size: positive if long and negative if short.
+	Size         float64
+	AveragePrice float64
+	// Mark is the price that the open lots are valued at, if Marked.
+	Mark   float64
+	Marked bool
+	// Realized, Unrealized, and Fees are in the quote currency. Unrealized is zero unless Marked.
+	Realized   float64
+	Unrealized float64
+	Fees       float64
+}
+
+// Report summarizes the positions of all products, ordered by product, with open lots valued at the mark prices by
+// product (e.g., {"BTC-USD": 10000}).
+func (pnl *PnL) Report(marks map[string]float64) []ProductPnL {
+	report := make([]ProductPnL, 0, len(pnl.positions))
+	for productID, position := range pnl.positions {
+		summary := ProductPnL{
+			ProductID:    productID,
+			Size:         position.Size(),
+			AveragePrice: position.AveragePrice(),
+			Realized:     position.Realized,
+			Fees:         position.Fees,
+		}
+		if mark, ok := marks[productID]; ok {
+			summary.Mark, summary.Marked = mark, true
+			summary.Unrealized = position.Unrealized(mark)
+		}
+		report = append(report, summary)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].ProductID < report[j].ProductID })
+	return report
+}
+
+// sortFills sorts fills in the order they occurred.
+func sortFills(fills []*Fill) {
+	sort.SliceStable(fills, func(i, j int) bool {
+		ti, tj := fills[i].CreatedAt, fills[j].CreatedAt
+		if ti != nil && tj != nil && !ti.Equal(*tj) {
+			return ti.Before(*tj)
+		}
+		return fills[i].TradeID < fills[j].TradeID
+	})
+}
@@ -0,0 +1,110 @@
+package gdax_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+const pnlFillsJSON = `
+	[
+	    {"trade_id": 75, "product_id": "BTC-USD", "price": "12.00", "size": "1.00", "created_at": "2014-11-08T22:19:28.578544Z", "liquidity": "T", "fee": "0.10", "settled": true, "side": "sell"},
+	    {"trade_id": 74, "product_id": "BTC-USD", "price": "10.00", "size": "2.00", "created_at": "2014-11-07T22:19:28.578544Z", "liquidity": "M", "fee": "0.00", "settled": true, "side": "buy"}
+	]
+`
+
+// fill creates a fill of BTC-USD on the specified day of 2018.
+func fill(tradeID int64, day int, side string, price, size, fee float64) *gdax.Fill {
+	at := time.Date(2018, 1, day, 0, 0, 0, 0, time.UTC)
+	return &gdax.Fill{TradeID: tradeID, ProductID: "BTC-USD", CreatedAt: &at, Side: side, Price: price, Size: size, Fee: fee}
+}
+
+func TestPnLMethods(t *testing.T) {
+	assert := assert.New(t)
+
+	fills := []*gdax.Fill{
+		fill(1, 1, gdax.Buy, 100, 1, 1),
+		fill(2, 2, gdax.Buy, 200, 1, 2),
+		fill(3, 3, gdax.Sell, 300, 1.5, 4.5),
+	}
+	for _, test := range []struct {
+		method     gdax.LotMethod
+		realized   float64
+		price      float64
+		unrealized float64
+	}{
+		{gdax.FIFO, 196 + 47.5, 202, 24},
+		{gdax.LIFO, 95 + 98, 101, 74.5},
+		{gdax.AverageCost, 218.25, 151.5, 49.25},
+	} {
+		pnl := gdax.NewPnL(test.method)
+		for _, fill := range fills {
+			assert.NoError(pnl.Add(fill))
+		}
+		report := pnl.Report(map[string]float64{"BTC-USD": 250})
+		assert.Len(report, 1, test.method.String())
+		assert.InDelta(report[0].Realized, test.realized, 1e-9, test.method.String())
+		assert.InDelta(report[0].Unrealized, test.unrealized, 1e-9, test.method.String())
+		assert.InDelta(report[0].AveragePrice, test.price, 1e-9, test.method.String())
+		assert.InDelta(report[0].Size, 0.5, 1e-9, test.method.String())
+		assert.Equal(report[0].Fees, 7.5)
+	}
+
+	pnl := gdax.NewPnL(gdax.FIFO)
+	assert.NoError(pnl.Add(fill(1, 1, gdax.Buy, 100, 1, 0)))
+	assert.NoError(pnl.Add(fill(2, 2, gdax.Buy, 200, 1, 0)))
+	assert.NoError(pnl.Add(fill(3, 3, gdax.Sell, 300, 1.5, 0)))
+	disposals := pnl.Position("BTC-USD").Disposals
+	assert.Len(disposals, 2)
+	assert.Equal(disposals[0].Opened, time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(disposals[1].Size, 0.5)
+	assert.Equal(disposals[1].Cost, 100.0)
+	assert.Equal(disposals[1].Proceeds, 150.0)
+
+	assert.Error(pnl.Add(fill(4, 4, "hold", 1, 1, 0)))
+	assert.Error(pnl.Add(fill(5, 5, gdax.Buy, 1, 0, 0)))
+}
+
+func TestPnLShort(t *testing.T) {
+	assert := assert.New(t)
+
+	pnl := gdax.NewPnL(gdax.FIFO)
+	assert.NoError(pnl.Add(fill(1, 1, gdax.Sell, 10, 1, 0)))
+	position := pnl.Position("BTC-USD")
+	assert.Equal(position.Size(), -1.0)
+	assert.Equal(position.Unrealized(8), 2.0)
+
+	// buying more than the short closes it and opens a long lot with the rest.
+	assert.NoError(pnl.Add(fill(2, 2, gdax.Buy, 8, 2, 0)))
+	assert.Equal(position.Realized, 2.0)
+	assert.Equal(position.Size(), 1.0)
+	assert.Equal(position.Lots[0].Side, gdax.Buy)
+	assert.Equal(position.Lots[0].Price, 8.0)
+}
+
+func TestPnLAddAll(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		Reply(http.StatusOK).
+		BodyString(pnlFillsJSON)
+
+	pnl := gdax.NewPnL(gdax.FIFO)
+	assert.NoError(pnl.AddAll(context.Background(), accessInfo.GetFills()))
+	report := pnl.Report(nil)
+	assert.Len(report, 1)
+	assert.InDelta(report[0].Realized, 1.9, 1e-9)
+	assert.Equal(report[0].Size, 1.0)
+	assert.False(report[0].Marked)
+	assert.Equal(report[0].Unrealized, 0.0)
+}
@@ -0,0 +1,311 @@
+package gdax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNoPrice is returned by a PriceSource that does not know the price of a product yet.
+var ErrNoPrice = errors.New("no price")
+
+// A PriceSource gets the last price of a product (e.g., 10000 for BTC-USD).
+type PriceSource interface {
+	Price(ctx context.Context, productID string) (float64, error)
+}
+
+// LivePrices is a PriceSource of the last prices seen on the feed, from Ticker and Match messages.
+type LivePrices struct {
+	mu     sync.Mutex
+	prices map[string]float64
+}
+
+// NewLivePrices creates a LivePrices without any prices.
+func NewLivePrices() *LivePrices {
+	return &LivePrices{prices: make(map[string]float64)}
+}
+
+// Handle updates the price of a product from a Ticker or Match. Other messages are ignored.
+func (prices *LivePrices) Handle(m Message) {
+	prices.mu.Lock()
+	defer prices.mu.Unlock()
+	switch m := m.(type) {
+	case Ticker:
+		prices.prices[m.ProductID] = m.Price
+	case Match:
+		prices.prices[m.ProductID] = m.Price
+	}
+}
+
+// Price gets the last price of a product, or ErrNoPrice if no Ticker or Match of the product has been handled.
+func (prices *LivePrices) Price(ctx context.Context, productID string) (float64, error) {
+	prices.mu.Lock()
+	defer prices.mu.Unlock()
+	price, ok := prices.prices[productID]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrNoPrice, productID)
+	}
+	return price, nil
+}
+
+// A restPrices is a PriceSource that gets the ticker of a product for every price.
+type restPrices struct {
+	mu         sync.Mutex
+	accessInfo *AccessInfo
+	limiter    rateLimiter
+}
+
+// RESTPrices creates a PriceSource that gets prices from the ticker of each product, keeping within the public rate
+// limit.
+func RESTPrices(accessInfo *AccessInfo) PriceSource {
+	return &restPrices{accessInfo: accessInfo, limiter: rateLimiter{interval: time.Second / publicRequestsPerSecond}}
+}
+
+// Price gets the price of the last trade of a product.
+func (prices *restPrices) Price(ctx context.Context, productID string) (float64, error) {
+	prices.mu.Lock()
+	err := prices.limiter.wait(ctx)
+	prices.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	ticker, err := prices.accessInfo.getTicker(ctx, productID)
+	if err != nil {
+		return 0, err
+	}
+	return ticker.Price, nil
+}
+
+// A conversion converts an amount of one currency to another with the price of a product.
+// If inverse, the amount is in the quote currency of the product and is divided by the price.
+type conversion struct {
+	productID string
+	inverse   bool
+	to        string
+}
+
+// A Portfolio values the accounts of a Trader in a quote currency.
+type Portfolio struct {
+	trader Trader
+	prices PriceSource
+	// edges are the conversions from each currency.
+	edges map[string][]conversion
+}
+
+// NewPortfolio creates a Portfolio of the accounts of a Trader (e.g., an AccessInfo or a PaperTrader), whose
+// currencies are converted with the specified products (e.g., from GetProducts) at prices from the PriceSource.
+// Products with a status other than OnlineProduct are not used.
+func NewPortfolio(trader Trader, products []Product, prices PriceSource) *Portfolio {
+	var sorted []Product
+	for _, product := range products {
+		if product.Status == "" || product.Status == OnlineProduct {
+			sorted = append(sorted, product)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	edges := make(map[string][]conversion)
+	for _, product := range sorted {
+		edges[product.BaseCurrency] = append(edges[product.BaseCurrency],
+			conversion{productID: product.ID, to: product.QuoteCurrency})
+		edges[product.QuoteCurrency] = append(edges[product.QuoteCurrency],
+			conversion{productID: product.ID, inverse: true, to: product.BaseCurrency})
+	}
+	return &Portfolio{trader: trader, prices: prices, edges: edges}
+}
+
+// An AssetValue is the value of the balance of one currency in the quote currency of a Valuation.
+type AssetValue struct {
+	Currency string
+	// Balance, Available, and Holds are amounts of the currency, summed over its accounts.
+	Balance   float64
+	Available float64
+	Holds     float64
+	// Price is the value of one unit of the currency in the quote currency.
+	Price float64
+	// Value, AvailableValue, and HeldValue are the Balance, Available, and Holds in the quote currency.
+	Value          float64
+	AvailableValue float64
+	HeldValue      float64
+	// Weight is the Value as a fraction of the total value of the portfolio.
+	Weight float64
+	// Path are the products that the currency is converted through (e.g., ["ETH-BTC", "BTC-USD"]).
+	// It is empty for the quote currency.
+	Path []string
+}
+
+// A Valuation is the value of a Portfolio in a quote currency.
+type Valuation struct {
+	QuoteCurrency string
+	// Total, Available, and Held are the sums of the Value, AvailableValue, and HeldValue of the assets.
+	Total     float64
+	Available float64
+	Held      float64
+	// Assets are the currencies with a balance that could be valued, most valuable first.
+	Assets []AssetValue
+	// Unpriced are the currencies with a balance that could not be valued, because no products convert them to the
+	// quote currency or because every route has a price that is not known or cannot be looked up. They are not counted
+	// in the Total.
+	Unpriced []string
+}
+
+// Value values all accounts with a balance in the quote currency. Currencies without a priced product in the quote
+// currency are converted through intermediate currencies, with the fewest conversions whose prices are known. Each
+// price is looked up at most once per call; a product whose price cannot be looked up (e.g., because it was delisted)
+// is not used.
+func (portfolio *Portfolio) Value(ctx context.Context, quoteCurrency string) (*Valuation, error) {
+	accounts, err := portfolio.trader.GetAccounts().Collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	assets := make(map[string]*AssetValue)
+	var currencies []string
+	for _, account := range accounts {
+		asset, ok := assets[account.Currency]
+		if !ok {
+			asset = &AssetValue{Currency: account.Currency}
+			assets[account.Currency] = asset
+			currencies = append(currencies, account.Currency)
+		}
+		asset.Balance += account.Balance
+		asset.Available += account.Available
+		asset.Holds += account.Holds
+	}
+	sort.Strings(currencies)
+
+	valuation := Valuation{QuoteCurrency: quoteCurrency}
+	prices := &priceCache{source: portfolio.prices, prices: make(map[string]float64), unpriced: make(map[string]bool)}
+	for _, currency := range currencies {
+		asset := assets[currency]
+		if asset.Balance == 0 && asset.Holds == 0 {
+			continue
+		}
+		price, path, err := portfolio.convert(ctx, prices, currency, quoteCurrency)
+		if errors.Is(err, ErrNoPrice) {
+			valuation.Unpriced = append(valuation.Unpriced, currency)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		asset.Price = price
+		asset.Path = path
+		asset.Value = asset.Balance * price
+		asset.AvailableValue = asset.Available * price
+		asset.HeldValue = asset.Holds * price
+		valuation.Total += asset.Value
+		valuation.Available += asset.AvailableValue
+		valuation.Held += asset.HeldValue
+		valuation.Assets = append(valuation.Assets, *asset)
+	}
+	for idx := range valuation.Assets {
+		if valuation.Total != 0 {
+			valuation.Assets[idx].Weight = valuation.Assets[idx].Value / valuation.Total
+		}
+	}
+	sort.SliceStable(valuation.Assets, func(i, j int) bool { return valuation.Assets[i].Value > valuation.Assets[j].Value })
+	return &valuation, nil
+}
+
+// A priceCache remembers the prices looked up from a PriceSource, and the products whose prices could not be.
+type priceCache struct {
+	source   PriceSource
+	prices   map[string]float64
+	unpriced map[string]bool
+}
+
+// price gets the price of a product, looking it up if it has not been yet. It returns false if the price cannot be
+// looked up for any reason other than the context being done.
+func (cache *priceCache) price(ctx context.Context, productID string) (float64, bool, error) {
+	if price, ok := cache.prices[productID]; ok {
+		return price, true, nil
+	}
+	if cache.unpriced[productID] {
+		return 0, false, nil
+	}
+	price, err := cache.source.Price(ctx, productID)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return 0, false, ctxErr
+	}
+	if err != nil || price <= 0 {
+		cache.unpriced[productID] = true
+		return 0, false, nil
+	}
+	cache.prices[productID] = price
+	return price, true, nil
+}
+
+// convert gets the value of one unit of a currency in another, and the products it is converted through, with the
+// fewest conversions whose prices are known. The shortest route is priced first; if a price along it cannot be looked
+// up, the next shortest route without that product is tried. ErrNoPrice is returned if no route of known prices
+// connects the currencies.
+func (portfolio *Portfolio) convert(ctx context.Context, prices *priceCache, from, to string) (float64, []string, error) {
+	for {
+		route := portfolio.route(from, to, prices.unpriced)
+		if route == nil {
+			return 0, nil, fmt.Errorf("%w: no priced products convert %s to %s", ErrNoPrice, from, to)
+		}
+		rate := 1.0
+		var path []string
+		for _, step := range route {
+			price, ok, err := prices.price(ctx, step.productID)
+			if err != nil {
+				return 0, nil, err
+			}
+			if !ok {
+				break
+			}
+			if step.inverse {
+				rate /= price
+			} else {
+				rate *= price
+			}
+			path = append(path, step.productID)
+		}
+		if len(path) == len(route) {
+			return rate, path, nil
+		}
+	}
+}
+
+// route finds the shortest sequence of conversions from one currency to another that does not use the excluded
+// products, or nil if there is none. A conversion directly to the target currency is preferred over others of the
+// same length. The route from a currency to itself is empty.
+func (portfolio *Portfolio) route(from, to string, excluded map[string]bool) []conversion {
+	if from == to {
+		return []conversion{}
+	}
+	// previous is the conversion into each currency that has been reached, and the currency it converts from.
+	type arrival struct {
+		edge conversion
+		from string
+	}
+	previous := map[string]arrival{from: {}}
+	queue := []string{from}
+	for len(queue) > 0 {
+		currency := queue[0]
+		queue = queue[1:]
+		for _, edge := range portfolio.edges[currency] {
+			if edge.to != to || excluded[edge.productID] {
+				continue
+			}
+			route := []conversion{edge}
+			for at := currency; at != from; at = previous[at].from {
+				route = append(route, previous[at].edge)
+			}
+			slices.Reverse(route)
+			return route
+		}
+		for _, edge := range portfolio.edges[currency] {
+			if _, seen := previous[edge.to]; seen || excluded[edge.productID] {
+				continue
+			}
+			previous[edge.to] = arrival{edge: edge, from: currency}
+			queue = append(queue, edge.to)
+		}
+	}
+	return nil
+}
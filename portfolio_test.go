@@ -0,0 +1,196 @@
+package gdax_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+const (
+	productsJSON = `
+		[
+		    {"id": "BTC-USD", "base_currency": "BTC", "quote_currency": "USD", "base_min_size": "0.001", "base_max_size": "10000.00", "quote_increment": "0.01"},
+		    {"id": "ETH-BTC", "base_currency": "ETH", "quote_currency": "BTC", "base_min_size": "0.01", "base_max_size": "1000000.00", "quote_increment": "0.00001"}
+		]
+	`
+	productTickerJSON = `
+		{"trade_id": 4729088, "price": "333.99", "size": "0.193", "bid": "333.98", "ask": "333.99", "volume": "5957.11914015", "time": "2015-11-14T20:46:03.511254Z"}
+	`
+)
+
+func TestGetProducts(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/products").
+		Reply(http.StatusOK).
+		BodyString(productsJSON)
+
+	products, err := accessInfo.GetProducts()
+	assert.NoError(err)
+	assert.Len(products, 2)
+	assert.Equal(products[1].ID, "ETH-BTC")
+	assert.Equal(products[1].QuoteCurrency, "BTC")
+	assert.Equal(products[1].QuoteIncrement, 0.00001)
+}
+
+func TestGetTicker(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/products/BTC-USD/ticker").
+		Times(2).
+		Reply(http.StatusOK).
+		BodyString(productTickerJSON)
+
+	ticker, err := accessInfo.GetTicker("BTC-USD")
+	assert.NoError(err)
+	assert.Equal(ticker.TradeID, int64(4729088))
+	assert.Equal(ticker.Bid, 333.98)
+
+	price, err := gdax.RESTPrices(accessInfo).Price(context.Background(), "BTC-USD")
+	assert.NoError(err)
+	assert.Equal(price, 333.99)
+}
+
+func TestPortfolio(t *testing.T) {
+	assert := assert.New(t)
+
+	trader := gdax.NewPaperTrader(&gdax.PaperTraderOptions{
+		Balances: map[string]float64{"USD": 100, "BTC": 1, "ETH": 10, "XYZ": 5},
+	})
+	var snapshot gdax.Snapshot
+	snapshot.Type = gdax.SnapshotType
+	snapshot.ProductID = "BTC-USD"
+	snapshot.Asks = []gdax.Ask{{Price: 20000, Size: 1}}
+	trader.Handle(snapshot)
+	_, err := trader.PlaceLimitOrder(&gdax.Order{Side: gdax.Buy, ProductID: "BTC-USD", Price: 5000, Size: 0.01})
+	assert.NoError(err)
+
+	prices := gdax.NewLivePrices()
+	var ticker gdax.Ticker
+	ticker.Type = gdax.TickerType
+	ticker.ProductID = "BTC-USD"
+	ticker.Price = 10000
+	prices.Handle(ticker)
+	var ethMatch gdax.Match
+	ethMatch.Type = gdax.MatchType
+	ethMatch.ProductID = "ETH-BTC"
+	ethMatch.Price = 0.05
+	prices.Handle(ethMatch)
+
+	products := []gdax.Product{
+		{ID: "BTC-USD", BaseCurrency: "BTC", QuoteCurrency: "USD"},
+		{ID: "ETH-BTC", BaseCurrency: "ETH", QuoteCurrency: "BTC"},
+		{ID: "XYZ-EUR", BaseCurrency: "XYZ", QuoteCurrency: "EUR"},
+	}
+	portfolio := gdax.NewPortfolio(trader, products, prices)
+
+	valuation, err := portfolio.Value(context.Background(), "USD")
+	assert.NoError(err)
+	assert.Equal(valuation.Total, 15100.0)
+	assert.Equal(valuation.Held, 50.0)
+	assert.Equal(valuation.Available, 15050.0)
+	assert.Equal(valuation.Unpriced, []string{"XYZ"})
+	assert.Len(valuation.Assets, 3)
+
+	btc, eth, usd := valuation.Assets[0], valuation.Assets[1], valuation.Assets[2]
+	assert.Equal(btc.Currency, "BTC")
+	assert.Equal(btc.Path, []string{"BTC-USD"})
+	assert.InDelta(btc.Weight, 10000/15100.0, 1e-9)
+	assert.Equal(eth.Currency, "ETH")
+	assert.Equal(eth.Path, []string{"ETH-BTC", "BTC-USD"})
+	assert.InDelta(eth.Value, 5000, 1e-9)
+	assert.Equal(usd.Currency, "USD")
+	assert.Empty(usd.Path)
+	assert.Equal(usd.HeldValue, 50.0)
+	assert.Equal(usd.AvailableValue, 50.0)
+
+	// converting to BTC divides by the price of BTC-USD.
+	valuation, err = portfolio.Value(context.Background(), "BTC")
+	assert.NoError(err)
+	assert.InDelta(valuation.Total, 1.51, 1e-9)
+}
+
+func TestPortfolioPricedRoute(t *testing.T) {
+	assert := assert.New(t)
+
+	trader := gdax.NewPaperTrader(&gdax.PaperTraderOptions{Balances: map[string]float64{"XYZ": 2}})
+	prices := gdax.NewLivePrices()
+	for productID, price := range map[string]float64{"XYZ-BTC": 0.001, "BTC-USD": 10000} {
+		var ticker gdax.Ticker
+		ticker.Type = gdax.TickerType
+		ticker.ProductID = productID
+		ticker.Price = price
+		prices.Handle(ticker)
+	}
+	products := []gdax.Product{
+		{ID: "BTC-USD", BaseCurrency: "BTC", QuoteCurrency: "USD"},
+		{ID: "XYZ-BTC", BaseCurrency: "XYZ", QuoteCurrency: "BTC"},
+		{ID: "XYZ-USD", BaseCurrency: "XYZ", QuoteCurrency: "USD"},
+	}
+	portfolio := gdax.NewPortfolio(trader, products, prices)
+
+	// XYZ-USD has no price yet, so XYZ is converted through BTC instead.
+	valuation, err := portfolio.Value(context.Background(), "USD")
+	assert.NoError(err)
+	assert.Empty(valuation.Unpriced)
+	assert.Len(valuation.Assets, 1)
+	assert.Equal(valuation.Assets[0].Path, []string{"XYZ-BTC", "BTC-USD"})
+	assert.InDelta(valuation.Total, 20, 1e-9)
+}
+
+// A countingPrices is a PriceSource that counts the lookups of each product and fails for products without a price.
+type countingPrices struct {
+	prices  map[string]float64
+	lookups map[string]int
+}
+
+func (prices *countingPrices) Price(ctx context.Context, productID string) (float64, error) {
+	prices.lookups[productID]++
+	price, ok := prices.prices[productID]
+	if !ok {
+		return 0, errors.New("404 Not Found")
+	}
+	return price, nil
+}
+
+func TestPortfolioFailedPrice(t *testing.T) {
+	assert := assert.New(t)
+
+	trader := gdax.NewPaperTrader(&gdax.PaperTraderOptions{Balances: map[string]float64{"ABC": 5, "BTC": 1, "XYZ": 2}})
+	prices := &countingPrices{
+		prices:  map[string]float64{"BTC-EUR": 9000, "BTC-USD": 10000, "XYZ-BTC": 0.001, "ABC-USD": 1},
+		lookups: make(map[string]int),
+	}
+	products := []gdax.Product{
+		{ID: "ABC-USD", BaseCurrency: "ABC", QuoteCurrency: "USD", Status: "delisted"},
+		{ID: "BTC-EUR", BaseCurrency: "BTC", QuoteCurrency: "EUR", Status: gdax.OnlineProduct},
+		{ID: "BTC-USD", BaseCurrency: "BTC", QuoteCurrency: "USD", Status: gdax.OnlineProduct},
+		{ID: "XYZ-BTC", BaseCurrency: "XYZ", QuoteCurrency: "BTC", Status: gdax.OnlineProduct},
+		{ID: "XYZ-USD", BaseCurrency: "XYZ", QuoteCurrency: "USD", Status: gdax.OnlineProduct},
+	}
+	portfolio := gdax.NewPortfolio(trader, products, prices)
+
+	// the lookup of XYZ-USD fails, so XYZ is converted through BTC, whose price is only looked up once. ABC is only
+	// converted by a delisted product, so it is not priced.
+	valuation, err := portfolio.Value(context.Background(), "USD")
+	assert.NoError(err)
+	assert.Equal(valuation.Unpriced, []string{"ABC"})
+	assert.InDelta(valuation.Total, 10020, 1e-9)
+	assert.Equal(valuation.Assets[1].Path, []string{"XYZ-BTC", "BTC-USD"})
+	assert.Equal(prices.lookups, map[string]int{"BTC-USD": 1, "XYZ-USD": 1, "XYZ-BTC": 1})
+}
@@ -0,0 +1,64 @@
+package gdax
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Product Statuses
+const (
+	OnlineProduct = "online"
+)
+
+// A Product represents a currency pair that can be traded (e.g., BTC-USD, where BTC is the base currency and USD is
+// the quote currency).
+type Product struct {
+	ID             string  `json:"id"`
+	BaseCurrency   string  `json:"base_currency"`
+	QuoteCurrency  string  `json:"quote_currency"`
+	BaseMinSize    float64 `json:"base_min_size,string"`
+	BaseMaxSize    float64 `json:"base_max_size,string"`
+	QuoteIncrement float64 `json:"quote_increment,string"`
+	DisplayName    string  `json:"display_name,omitempty"`
+	Status         string  `json:"status,omitempty"`
+}
+
+// A ProductTicker is a snapshot of the last trade, best bid and ask, and 24-hour volume of a product.
+type ProductTicker struct {
+	TradeID int64      `json:"trade_id"`
+	Price   float64    `json:"price,string"`
+	Size    float64    `json:"size,string"`
+	Bid     float64    `json:"bid,string"`
+	Ask     float64    `json:"ask,string"`
+	Volume  float64    `json:"volume,string"`
+	Time    *time.Time `json:"time,string"`
+}
+
+// GetProducts gets all products that can be traded.
+func (accessInfo *AccessInfo) GetProducts() ([]Product, error) {
+	// GET /products
+	var products []Product
+	_, err := accessInfo.request(http.MethodGet, "/products", "", &products)
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// GetTicker gets the ticker of the product with the specified productID.
+func (accessInfo *AccessInfo) GetTicker(productID string) (*ProductTicker, error) {
+	return accessInfo.getTicker(context.Background(), productID)
+}
+
+// getTicker is like GetTicker, but the request is canceled once the context is done.
+func (accessInfo *AccessInfo) getTicker(ctx context.Context, productID string) (*ProductTicker, error) {
+	// GET /products/<product-id>/ticker
+	var ticker ProductTicker
+	_, err := accessInfo.requestWithContext(ctx, http.MethodGet, fmt.Sprintf("/products/%s/ticker", productID), "", &ticker)
+	if err != nil {
+		return nil, err
+	}
+	return &ticker, nil
+}
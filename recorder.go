@@ -0,0 +1,256 @@
+package gdax
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// recordingHeader starts every recording, followed by the format version.
+const (
+	recordingHeader  = "GDAXREC"
+	recordingVersion = 1
+	// maxRecordedFrame guards against allocating absurd frames when reading a corrupt recording.
+	maxRecordedFrame = 64 << 20
+)
+
+// AsFastAsPossible is the replay speed that replays frames without waiting between them.
+const AsFastAsPossible = 0
+
+// ErrNotRecording is returned when a file is not a feed recording.
+var ErrNotRecording = errors.New("not a feed recording")
+
+// A Recorder writes raw feed frames and the time they were received to an append-only recording.
+// Each frame is stored as a varint timestamp (Unix nanoseconds), a uvarint length, and the frame itself.
+// A Recorder is safe for concurrent use, so a single Recorder can be shared by several streams.
+type Recorder struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	gz     *gzip.Writer
+	closer io.Closer
+	buf    [2 * binary.MaxVarintLen64]byte
+}
+
+// NewRecorder creates a Recorder that writes a new recording to the specified writer.
+// If compress is true, the recording is gzipped.
+func NewRecorder(w io.Writer, compress bool) (*Recorder, error) {
+	return newRecorder(w, nil, compress, true)
+}
+
+// CreateRecording opens the recording at the specified path for appending, creating it if it does not exist.
+// If compress is true, the recording is gzipped; each session appended to a compressed recording is a separate gzip member.
+// An existing recording must have been created with the same compress setting.
+func CreateRecording(path string, compress bool) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.Size() > 0 {
+		magic := make([]byte, 2)
+		if _, err := file.ReadAt(magic, 0); err != nil {
+			file.Close()
+			return nil, ErrNotRecording
+		}
+		if isGzip(magic) != compress {
+			file.Close()
+			return nil, fmt.Errorf("cannot append to %s: compression does not match the existing recording", path)
+		}
+	}
+	recorder, err := newRecorder(file, file, compress, info.Size() == 0)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return recorder, nil
+}
+
+// newRecorder creates a Recorder, writing the recording header if it is a new recording.
+func newRecorder(w io.Writer, closer io.Closer, compress, header bool) (*Recorder, error) {
+	recorder := Recorder{closer: closer}
+	if compress {
+		recorder.gz = gzip.NewWriter(w)
+		w = recorder.gz
+	}
+	recorder.w = bufio.NewWriter(w)
+	if header {
+		if _, err := recorder.w.WriteString(recordingHeader); err != nil {
+			return nil, err
+		}
+		if err := recorder.w.WriteByte(recordingVersion); err != nil {
+			return nil, err
+		}
+	}
+	return &recorder, nil
+}
+
+// Record appends a single frame to the recording.
+// Frames are buffered; call Flush or Close to make sure they are written.
+func (recorder *Recorder) Record(frame []byte, receivedAt time.Time) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	n := binary.PutVarint(recorder.buf[:], receivedAt.UnixNano())
+	n += binary.PutUvarint(recorder.buf[n:], uint64(len(frame)))
+	if _, err := recorder.w.Write(recorder.buf[:n]); err != nil {
+		return err
+	}
+	_, err := recorder.w.Write(frame)
+	return err
+}
+
+// Flush writes any buffered frames to the underlying writer.
+func (recorder *Recorder) Flush() error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if err := recorder.w.Flush(); err != nil {
+		return err
+	}
+	if recorder.gz != nil {
+		return recorder.gz.Flush()
+	}
+	return nil
+}
+
+// Close flushes the recording and closes the file, if the Recorder opened it.
+func (recorder *Recorder) Close() error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	err := recorder.w.Flush()
+	if recorder.gz != nil {
+		if gzErr := recorder.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	if recorder.closer != nil {
+		if closeErr := recorder.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// A RecordedFrame is a single raw feed frame read from a recording.
+type RecordedFrame struct {
+	ReceivedAt time.Time
+	Frame      []byte
+}
+
+// A RecordingReader reads the frames of a recording, compressed or not.
+type RecordingReader struct {
+	r *bufio.Reader
+}
+
+// NewRecordingReader creates a RecordingReader and checks the recording header.
+func NewRecordingReader(r io.Reader) (*RecordingReader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil {
+		return nil, ErrNotRecording
+	}
+	if isGzip(magic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(gz)
+	}
+	header := make([]byte, len(recordingHeader)+1)
+	if _, err := io.ReadFull(br, header); err != nil || !bytes.Equal(header[:len(recordingHeader)], []byte(recordingHeader)) {
+		return nil, ErrNotRecording
+	}
+	if header[len(recordingHeader)] != recordingVersion {
+		return nil, fmt.Errorf("unsupported recording version: %d", header[len(recordingHeader)])
+	}
+	return &RecordingReader{r: br}, nil
+}
+
+// Next reads the next frame of the recording.
+// At the end of the recording, io.EOF is returned; a frame that was only partially written returns io.ErrUnexpectedEOF.
+func (reader *RecordingReader) Next() (RecordedFrame, error) {
+	nanos, err := binary.ReadVarint(reader.r)
+	if err != nil {
+		return RecordedFrame{}, err
+	}
+	length, err := binary.ReadUvarint(reader.r)
+	if err == io.EOF {
+		return RecordedFrame{}, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return RecordedFrame{}, err
+	}
+	if length > maxRecordedFrame {
+		return RecordedFrame{}, fmt.Errorf("recorded frame is too large: %d bytes", length)
+	}
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(reader.r, frame); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return RecordedFrame{}, err
+	}
+	return RecordedFrame{ReceivedAt: time.Unix(0, nanos), Frame: frame}, nil
+}
+
+// Replay decodes the frames of a recording and sends them to the specified message handler.
+// A speed of 1 replays the frames with their original timing, a speed of 10 replays them ten times faster,
+// and AsFastAsPossible replays them without waiting.
+// Unlike Feed, Replay does not stop at Error messages; it terminates at the end of the recording, on a malformed
+// frame, or once the context is done.
+func Replay(ctx context.Context, r io.Reader, speed float64, messageHandler func(Message)) error {
+	if speed < 0 {
+		return fmt.Errorf("invalid replay speed: %v", speed)
+	}
+	reader, err := NewRecordingReader(r)
+	if err != nil {
+		return err
+	}
+	var first time.Time
+	start := time.Now()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		recorded, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if first.IsZero() {
+			first = recorded.ReceivedAt
+		}
+		if speed > 0 {
+			offset := time.Duration(float64(recorded.ReceivedAt.Sub(first)) / speed)
+			if wait := time.Until(start.Add(offset)); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+		message, err := decodeFrame(recorded.Frame)
+		if err != nil {
+			return err
+		}
+		messageHandler(message)
+	}
+}
+
+// isGzip determines if a file starts with the gzip magic number.
+func isGzip(magic []byte) bool {
+	return len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b
+}
@@ -0,0 +1,149 @@
+package gdax_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compress=%v", compress), func(t *testing.T) {
+			assert := assert.New(t)
+
+			var recording bytes.Buffer
+			recorder, err := gdax.NewRecorder(&recording, compress)
+			assert.NoError(err)
+			start := time.Now()
+			assert.NoError(recorder.Record([]byte(fmt.Sprintf(tickerJSON, "BTC-USD")), start))
+			assert.NoError(recorder.Record([]byte(matchJSON), start.Add(time.Second)))
+			assert.NoError(recorder.Record([]byte(doneJSON), start.Add(2*time.Second)))
+			assert.NoError(recorder.Close())
+
+			var messages []gdax.Message
+			err = gdax.Replay(context.Background(), &recording, gdax.AsFastAsPossible, func(m gdax.Message) {
+				messages = append(messages, m)
+			})
+			assert.NoError(err)
+			assert.Len(messages, 3)
+			assert.Equal(messages[0].(gdax.Ticker).ProductID, "BTC-USD")
+			assert.Equal(messages[1].(gdax.Match).TradeID, int64(10))
+			assert.Equal(messages[2].MessageType(), "done")
+		})
+	}
+}
+
+func TestReplaySpeed(t *testing.T) {
+	assert := assert.New(t)
+
+	var recording bytes.Buffer
+	recorder, err := gdax.NewRecorder(&recording, false)
+	assert.NoError(err)
+	start := time.Now()
+	assert.NoError(recorder.Record([]byte(matchJSON), start))
+	assert.NoError(recorder.Record([]byte(matchJSON), start.Add(time.Second)))
+	assert.NoError(recorder.Close())
+
+	var received []time.Time
+	began := time.Now()
+	err = gdax.Replay(context.Background(), &recording, 10, func(gdax.Message) {
+		received = append(received, time.Now())
+	})
+	assert.NoError(err)
+	assert.Len(received, 2)
+	assert.GreaterOrEqual(received[1].Sub(began), 100*time.Millisecond)
+	assert.Less(received[1].Sub(began), time.Second)
+}
+
+func TestReplayCanceled(t *testing.T) {
+	var recording bytes.Buffer
+	recorder, err := gdax.NewRecorder(&recording, false)
+	assert.NoError(t, err)
+	start := time.Now()
+	assert.NoError(t, recorder.Record([]byte(matchJSON), start))
+	assert.NoError(t, recorder.Record([]byte(matchJSON), start.Add(time.Hour)))
+	assert.NoError(t, recorder.Close())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = gdax.Replay(ctx, &recording, 1, func(gdax.Message) {})
+	assert.Equal(t, err, context.DeadlineExceeded)
+}
+
+func TestReplayTruncated(t *testing.T) {
+	var recording bytes.Buffer
+	recorder, err := gdax.NewRecorder(&recording, false)
+	assert.NoError(t, err)
+	assert.NoError(t, recorder.Record([]byte(matchJSON), time.Now()))
+	assert.NoError(t, recorder.Close())
+
+	truncated := bytes.NewReader(recording.Bytes()[:recording.Len()-5])
+	err = gdax.Replay(context.Background(), truncated, gdax.AsFastAsPossible, func(gdax.Message) {})
+	assert.Equal(t, err, io.ErrUnexpectedEOF)
+
+	err = gdax.Replay(context.Background(), bytes.NewReader([]byte(matchJSON)), gdax.AsFastAsPossible, func(gdax.Message) {})
+	assert.Equal(t, err, gdax.ErrNotRecording)
+}
+
+func TestCreateRecordingAppends(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "feed.rec.gz")
+
+	for _, frame := range []string{matchJSON, doneJSON} {
+		recorder, err := gdax.CreateRecording(path, true)
+		assert.NoError(err)
+		assert.NoError(recorder.Record([]byte(frame), time.Now()))
+		assert.NoError(recorder.Close())
+	}
+	_, err := gdax.CreateRecording(path, false)
+	assert.Error(err)
+
+	file, err := os.Open(path)
+	assert.NoError(err)
+	defer file.Close()
+	reader, err := gdax.NewRecordingReader(file)
+	assert.NoError(err)
+	first, err := reader.Next()
+	assert.NoError(err)
+	assert.Equal(string(first.Frame), matchJSON)
+	second, err := reader.Next()
+	assert.NoError(err)
+	assert.Equal(string(second.Frame), doneJSON)
+	_, err = reader.Next()
+	assert.Equal(err, io.EOF)
+}
+
+func TestStreamRecorder(t *testing.T) {
+	assert := assert.New(t)
+
+	server, endPoint := newFeedServer(t, sendFrames(matchJSON, doneJSON))
+	defer server.Close()
+
+	var recording bytes.Buffer
+	recorder, err := gdax.NewRecorder(&recording, true)
+	assert.NoError(err)
+	stream, err := gdax.Stream(context.Background(), &feedSubscription, &gdax.StreamOptions{
+		EndPoint: endPoint,
+		Recorder: recorder,
+	})
+	assert.NoError(err)
+	<-stream.Messages()
+	<-stream.Messages()
+	stream.Close()
+	assert.NoError(recorder.Close())
+
+	var replayed []string
+	err = gdax.Replay(context.Background(), &recording, gdax.AsFastAsPossible, func(m gdax.Message) {
+		replayed = append(replayed, m.MessageType())
+	})
+	assert.NoError(err)
+	assert.Equal(replayed, []string{gdax.MatchType, "done"})
+}
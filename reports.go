@@ -1,21 +1,48 @@
 package gdax
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/imdario/mergo"
 )
 
+// Report Types
+const (
+	Fills         = "fills"
+	AccountReport = "account"
+)
+
 // Formats
 const (
-	Fills = "fills"
-	Pdf   = "pdf"
-	Csv   = "csv"
+	Pdf = "pdf"
+	Csv = "csv"
+)
+
+// Report Statuses
+const (
+	Creating = "creating"
+	Ready    = "ready"
 )
 
+// Report polling backoff
+const (
+	minReportPollInterval = 500 * time.Millisecond
+	maxReportPollInterval = 30 * time.Second
+)
+
+// ErrReportExpired is returned when a report expires before it is ready or downloaded.
+var ErrReportExpired = errors.New("report expired")
+
 // A ReportParams stores the start and end date of a report response.
 type ReportParams struct {
 	StartDate *time.Time `json:"start_date,string,omitempty"`
@@ -42,6 +69,45 @@ type Report struct {
 	Params      *ReportParams `json:"params,omitempty"`
 }
 
+// A ReportCollection is an iterator of Reports.
+type ReportCollection = Iterator[*Report]
+
+// NewFillsReport builds a fills Report for the specified productID.
+// The format must be either Pdf or Csv; the email is optional.
+func NewFillsReport(productID string, startDate, endDate time.Time, format, email string) (*Report, error) {
+	report := Report{
+		Type:      Fills,
+		ProductID: productID,
+		Format:    format,
+		Email:     email,
+	}
+	if err := report.setDateRange(startDate, endDate); err != nil {
+		return nil, err
+	}
+	if err := report.validate(); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// NewAccountReport builds an account Report for the specified accountID.
+// The format must be either Pdf or Csv; the email is optional.
+func NewAccountReport(accountID *uuid.UUID, startDate, endDate time.Time, format, email string) (*Report, error) {
+	report := Report{
+		Type:      AccountReport,
+		AccountID: accountID,
+		Format:    format,
+		Email:     email,
+	}
+	if err := report.setDateRange(startDate, endDate); err != nil {
+		return nil, err
+	}
+	if err := report.validate(); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
 // CreateReport submits a report request.
 func (accessInfo *AccessInfo) CreateReport(report *Report) (*Report, error) {
 	// POST /reports
@@ -63,11 +129,278 @@ func (accessInfo *AccessInfo) CreateReport(report *Report) (*Report, error) {
 
 // GetReportStatus retrieves the status of a submitted report.
 func (accessInfo *AccessInfo) GetReportStatus(reportID *uuid.UUID) (*Report, error) {
+	return accessInfo.getReportStatus(context.Background(), reportID)
+}
+
+// getReportStatus is like GetReportStatus, but the request is canceled once the context is done.
+func (accessInfo *AccessInfo) getReportStatus(ctx context.Context, reportID *uuid.UUID) (*Report, error) {
 	// GET /reports/:report_id
 	var reportStatus Report
-	_, err := accessInfo.request(http.MethodGet, "/reports/"+reportID.String(), "", &reportStatus)
+	_, err := accessInfo.requestWithContext(ctx, http.MethodGet, "/reports/"+reportID.String(), "", &reportStatus)
 	if err != nil {
 		return nil, err
 	}
 	return &reportStatus, nil
 }
+
+// GetReports gets all previously created reports of the specified type (i.e., Fills or AccountReport).
+func (accessInfo *AccessInfo) GetReports(reportType string) *ReportCollection {
+	// GET /reports
+	var typeParam string
+	if reportType != "" {
+		typeParam = "type=" + reportType
+	}
+	return newIterator[*Report](accessInfo, http.MethodGet, "/reports", typeParam, "", true)
+}
+
+// WaitForReport polls the status of a submitted report with exponential backoff until it is ready.
+// This function returns ErrReportExpired if the report expires first, or the context's error if the context is done first.
+func (accessInfo *AccessInfo) WaitForReport(ctx context.Context, reportID *uuid.UUID) (*Report, error) {
+	interval := minReportPollInterval
+	for {
+		report, err := accessInfo.getReportStatus(ctx, reportID)
+		if err != nil {
+			return nil, err
+		}
+		if report.Status == Ready {
+			return report, nil
+		}
+		if report.expired() {
+			return nil, ErrReportExpired
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if interval *= 2; interval > maxReportPollInterval {
+			interval = maxReportPollInterval
+		}
+	}
+}
+
+// Download streams the file of a ready report to the specified writer. Unlike other requests, it is not limited by
+// the timeout of the HTTP client; use the context to limit it.
+func (accessInfo *AccessInfo) Download(ctx context.Context, report *Report, w io.Writer) error {
+	if report.FileURL == "" {
+		return fmt.Errorf("report %s has no file url (status: %s)", report.ID, report.Status)
+	}
+	if report.expired() {
+		return ErrReportExpired
+	}
+
+	// the file URL is presigned, so the request does not need to be signed.
+	req, err := http.NewRequest(http.MethodGet, report.FileURL, nil)
+	if err != nil {
+		return err
+	}
+	// the timeout of the client also limits reading the body, which is too short for a large report, so the
+	// download is only bound by the context.
+	client := *accessInfo.Client
+	client.Timeout = 0
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if !(http.StatusOK <= resp.StatusCode && resp.StatusCode < http.StatusMultipleChoices) {
+		return fmt.Errorf("could not download report %s: %s", report.ID, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// ParseFillsReport parses a fills report in the Csv format into Fills.
+func ParseFillsReport(r io.Reader) ([]Fill, error) {
+	var fills []Fill
+	err := parseCsvReport(r, func(row csvRow) error {
+		var (
+			fill Fill
+			err  error
+		)
+		if fill.TradeID, err = row.parseInt("trade id"); err != nil {
+			return err
+		}
+		fill.ProductID = row.get("product")
+		fill.Side = row.get("side")
+		if fill.CreatedAt, err = row.parseTime("created at"); err != nil {
+			return err
+		}
+		if fill.Size, err = row.parseFloat("size"); err != nil {
+			return err
+		}
+		if fill.Price, err = row.parseFloat("price"); err != nil {
+			return err
+		}
+		if fill.Fee, err = row.parseFloat("fee"); err != nil {
+			return err
+		}
+		fill.Settled = true
+		fills = append(fills, fill)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fills, nil
+}
+
+// ParseAccountReport parses an account report in the Csv format into AccountHistorys.
+func ParseAccountReport(r io.Reader) ([]AccountHistory, error) {
+	var histories []AccountHistory
+	err := parseCsvReport(r, func(row csvRow) error {
+		var history AccountHistory
+		createdAt, err := row.parseTime("time")
+		if err != nil {
+			return err
+		}
+		if createdAt != nil {
+			history.CreatedAt = *createdAt
+		}
+		history.Type = row.get("type")
+		if history.Amount, err = row.parseFloat("amount"); err != nil {
+			return err
+		}
+		if history.Balance, err = row.parseFloat("balance"); err != nil {
+			return err
+		}
+		history.Details.TradeID = row.get("trade id")
+		if orderID := row.get("order id"); orderID != "" {
+			parsedOrderID, err := uuid.Parse(orderID)
+			if err != nil {
+				return err
+			}
+			history.Details.OrderID = &parsedOrderID
+		}
+		histories = append(histories, history)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return histories, nil
+}
+
+// setDateRange sets the start and end date of a report request.
+func (report *Report) setDateRange(startDate, endDate time.Time) error {
+	if !startDate.Before(endDate) {
+		return fmt.Errorf("report start date %s is not before end date %s", startDate, endDate)
+	}
+	report.StartDate = &startDate
+	report.EndDate = &endDate
+	return nil
+}
+
+// validate determines if a report built by NewFillsReport or NewAccountReport can be submitted.
+// CreateReport does not validate reports, so that the server decides which requests it accepts.
+func (report *Report) validate() error {
+	switch report.Format {
+	case "", Pdf, Csv:
+	default:
+		return fmt.Errorf("unknown report format %q (expected %q or %q)", report.Format, Pdf, Csv)
+	}
+	switch report.Type {
+	case Fills:
+		if report.ProductID == "" {
+			return errors.New("a fills report requires a product id")
+		}
+	case AccountReport:
+		if report.AccountID == nil {
+			return errors.New("an account report requires an account id")
+		}
+	default:
+		return fmt.Errorf("unknown report type %q (expected %q or %q)", report.Type, Fills, AccountReport)
+	}
+	return nil
+}
+
+// expired determines if a report has passed its expiration time.
+func (report *Report) expired() bool {
+	return report.ExpiresAt != nil && !report.ExpiresAt.IsZero() && time.Now().After(*report.ExpiresAt)
+}
+
+// A csvRow is a single record of a Csv report, indexed by its header.
+type csvRow struct {
+	header map[string]int
+	record []string
+}
+
+// parseCsvReport reads a Csv report and calls the specified function on each record.
+func parseCsvReport(r io.Reader, f func(csvRow) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	columns, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	header := make(map[string]int, len(columns))
+	for idx, column := range columns {
+		header[strings.ToLower(strings.TrimSpace(column))] = idx
+	}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := f(csvRow{header: header, record: record}); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+	}
+}
+
+// get gets the value of the specified column, or the empty string if the column does not exist.
+func (row csvRow) get(column string) string {
+	idx, ok := row.header[column]
+	if !ok || idx >= len(row.record) {
+		return ""
+	}
+	return strings.TrimSpace(row.record[idx])
+}
+
+// parseFloat parses the value of the specified column as a float64.
+func (row csvRow) parseFloat(column string) (float64, error) {
+	value := row.get(column)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// parseInt parses the value of the specified column as an int64.
+func (row csvRow) parseInt(column string) (int64, error) {
+	value := row.get(column)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// parseTime parses the value of the specified column as an ISO 8601 time.
+func (row csvRow) parseTime(column string) (*time.Time, error) {
+	value := row.get(column)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// timestamp gets the creation time of a Report.
+func (report *Report) timestamp() time.Time {
+	if report.CreatedAt == nil {
+		return time.Time{}
+	}
+	return *report.CreatedAt
+}
@@ -0,0 +1,338 @@
+package gdax_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+const (
+	reportID          = "0428b97b-bec1-429e-a94c-59232926778d"
+	reportPendingJSON = `
+		{
+		    "id": "0428b97b-bec1-429e-a94c-59232926778d",
+		    "type": "fills",
+		    "status": "pending",
+		    "created_at": "2015-01-06T10:34:47.000Z",
+		    "expires_at": "2115-01-13T10:35:47.000Z"
+		}
+	`
+	reportReadyJSON = `
+		{
+		    "id": "0428b97b-bec1-429e-a94c-59232926778d",
+		    "type": "fills",
+		    "status": "ready",
+		    "created_at": "2015-01-06T10:34:47.000Z",
+		    "completed_at": "2015-01-06T10:35:47.000Z",
+		    "expires_at": "2115-01-13T10:35:47.000Z",
+		    "file_url": "https://example.com/0428b97b.csv"
+		}
+	`
+	reportExpiredJSON = `
+		{
+		    "id": "0428b97b-bec1-429e-a94c-59232926778d",
+		    "type": "fills",
+		    "status": "creating",
+		    "created_at": "2015-01-06T10:34:47.000Z",
+		    "expires_at": "2015-01-13T10:35:47.000Z"
+		}
+	`
+	fillsReportCsv = `portfolio,trade id,product,side,created at,size,size unit,price,fee,total,price/fee/total unit
+default,74,BTC-USD,BUY,2014-11-07T22:19:28.578Z,0.01,BTC,10.00,0.00025,-0.10025,USD
+default,75,BTC-USD,SELL,2014-11-07T22:20:28.578Z,0.02,BTC,9.00,0.00045,0.17955,USD
+`
+	accountReportCsv = `portfolio,type,time,amount,balance,amount/balance unit,transfer id,trade id,order id
+default,match,2014-11-07T22:19:28.578Z,-0.10000,79.90000,USD,,74,d50ec984-77a8-460a-b958-66f114b0de9b
+default,fee,2014-11-07T22:19:28.578Z,-0.00025,79.89975,USD,,74,d50ec984-77a8-460a-b958-66f114b0de9b
+default,deposit,2014-11-08T22:19:28.578Z,100.00000,179.89975,USD,3ffbf54d-5b47-4d5e-9bba-1b0e6ad1d07c,,
+`
+)
+
+func TestWaitForReport(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/reports/" + reportID).
+		Reply(http.StatusOK).
+		BodyString(reportPendingJSON)
+	gock.New(gdax.EndPoint).
+		Get("/reports/" + reportID).
+		Reply(http.StatusOK).
+		BodyString(reportReadyJSON)
+
+	parsedReportID, err := uuid.Parse(reportID)
+	assert.NoError(err)
+
+	report, err := accessInfo.WaitForReport(context.Background(), &parsedReportID)
+	assert.NoError(err)
+	assert.Equal(report.Status, gdax.Ready)
+	assert.Equal(report.FileURL, "https://example.com/0428b97b.csv")
+	assert.True(gock.IsDone())
+}
+
+func TestWaitForReportExpired(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/reports/" + reportID).
+		Reply(http.StatusOK).
+		BodyString(reportExpiredJSON)
+
+	parsedReportID, err := uuid.Parse(reportID)
+	assert.NoError(err)
+
+	report, err := accessInfo.WaitForReport(context.Background(), &parsedReportID)
+	assert.Equal(err, gdax.ErrReportExpired)
+	assert.Nil(report)
+}
+
+func TestWaitForReportCancelled(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/reports/" + reportID).
+		Persist().
+		Reply(http.StatusOK).
+		BodyString(reportPendingJSON)
+
+	parsedReportID, err := uuid.Parse(reportID)
+	assert.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	report, err := accessInfo.WaitForReport(ctx, &parsedReportID)
+	assert.Equal(err, context.DeadlineExceeded)
+	assert.Nil(report)
+}
+
+func TestWaitForReportCancelledDuringPoll(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/reports/" + reportID).
+		Reply(http.StatusOK).
+		Delay(time.Minute).
+		BodyString(reportReadyJSON)
+
+	parsedReportID, err := uuid.Parse(reportID)
+	assert.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	report, err := accessInfo.WaitForReport(ctx, &parsedReportID)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Nil(report)
+	assert.Less(time.Since(start), time.Second)
+}
+
+func TestDownload(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New("https://example.com").
+		Get("/0428b97b.csv").
+		Reply(http.StatusOK).
+		BodyString(fillsReportCsv)
+
+	report := gdax.Report{Status: gdax.Ready, FileURL: "https://example.com/0428b97b.csv"}
+	var buf bytes.Buffer
+	assert.NoError(accessInfo.Download(context.Background(), &report, &buf))
+	assert.Equal(buf.String(), fillsReportCsv)
+}
+
+func TestDownloadWithoutClientTimeout(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.NewAccessInfo("key", "c2VjcmV0", "passphrase", gdax.WithTimeout(10*time.Millisecond))
+	assert.NoError(err)
+
+	gock.New("https://example.com").
+		Get("/0428b97b.csv").
+		Times(2).
+		Reply(http.StatusOK).
+		Delay(50 * time.Millisecond).
+		BodyString(fillsReportCsv)
+
+	// a report can take longer to download than the timeout of the client, but not than the context allows.
+	report := gdax.Report{Status: gdax.Ready, FileURL: "https://example.com/0428b97b.csv"}
+	var buf bytes.Buffer
+	assert.NoError(accessInfo.Download(context.Background(), &report, &buf))
+	assert.Equal(buf.String(), fillsReportCsv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(accessInfo.Download(ctx, &report, &buf), context.DeadlineExceeded)
+}
+
+func TestParseFillsReport(t *testing.T) {
+	assert := assert.New(t)
+
+	fills, err := gdax.ParseFillsReport(strings.NewReader(fillsReportCsv))
+	assert.NoError(err)
+	assert.Len(fills, 2)
+
+	assert.Equal(fills[0].TradeID, int64(74))
+	assert.Equal(fills[0].ProductID, "BTC-USD")
+	assert.Equal(fills[0].Size, 0.01)
+	assert.Equal(fills[0].Price, 10.00)
+	assert.Equal(fills[0].Fee, 0.00025)
+	assert.Equal(fills[1].TradeID, int64(75))
+	assert.Equal(fills[1].CreatedAt.Minute(), 20)
+}
+
+func TestParseAccountReport(t *testing.T) {
+	assert := assert.New(t)
+
+	histories, err := gdax.ParseAccountReport(strings.NewReader(accountReportCsv))
+	assert.NoError(err)
+	assert.Len(histories, 3)
+
+	assert.Equal(histories[0].Type, gdax.MatchEntry)
+	assert.Equal(histories[0].Amount, -0.1)
+	assert.Equal(histories[0].Details.TradeID, "74")
+	assert.Equal(histories[0].Details.OrderID.String(), "d50ec984-77a8-460a-b958-66f114b0de9b")
+	assert.Equal(histories[1].Type, gdax.FeeEntry)
+	assert.Nil(histories[2].Details.OrderID)
+	assert.Equal(histories[2].Balance, 179.89975)
+}
+
+func TestParseFillsReportError(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := gdax.ParseFillsReport(strings.NewReader("trade id,size\nabc,1\n"))
+	assert.Error(err)
+}
+
+func TestNewFillsReport(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	report, err := gdax.NewFillsReport("BTC-USD", start, end, gdax.Csv, "")
+	assert.NoError(err)
+	assert.Equal(report.Type, gdax.Fills)
+	assert.Equal(report.ProductID, "BTC-USD")
+
+	_, err = gdax.NewFillsReport("", start, end, gdax.Csv, "")
+	assert.Error(err)
+
+	_, err = gdax.NewFillsReport("BTC-USD", start, end, "xls", "")
+	assert.Error(err)
+
+	_, err = gdax.NewFillsReport("BTC-USD", end, start, gdax.Pdf, "")
+	assert.Error(err)
+}
+
+func TestCreateAccountReport(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	const accountID = "6cf2b1ba-3705-40e6-a41e-69be033514f7"
+	gock.New(gdax.EndPoint).
+		Post("/reports").
+		MatchType("json").
+		JSON(map[string]string{
+			"type":       "account",
+			"account_id": accountID,
+			"format":     "csv",
+			"start_date": "2018-01-01T00:00:00Z",
+			"end_date":   "2018-02-01T00:00:00Z",
+		}).
+		Reply(http.StatusOK).
+		BodyString(`{"id": "0428b97b-bec1-429e-a94c-59232926778d", "type": "account", "status": "pending"}`)
+
+	parsedAccountID, err := uuid.Parse(accountID)
+	assert.NoError(err)
+
+	_, err = gdax.NewAccountReport(nil, time.Now(), time.Now().Add(time.Hour), gdax.Csv, "")
+	assert.Error(err)
+
+	start := time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC)
+	report, err := gdax.NewAccountReport(&parsedAccountID, start, start.AddDate(0, 1, 0), gdax.Csv, "")
+	assert.NoError(err)
+
+	report, err = accessInfo.CreateReport(report)
+	assert.NoError(err)
+	assert.Equal(report.ID.String(), reportID)
+	assert.Equal(*report.AccountID, parsedAccountID)
+	assert.Equal(report.Status, gdax.Pending)
+}
+
+func TestCreateReportUnvalidated(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	// reports that are not built by the typed constructors are sent as is.
+	gock.New(gdax.EndPoint).
+		Post("/reports").
+		BodyString(`"type":"fills"`).
+		Reply(http.StatusOK).
+		BodyString(`{"id": "0428b97b-bec1-429e-a94c-59232926778d", "type": "fills", "status": "pending"}`)
+
+	report, err := accessInfo.CreateReport(&gdax.Report{Type: gdax.Fills})
+	assert.NoError(err)
+	assert.Equal(report.ID.String(), reportID)
+}
+
+func TestGetReports(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/reports").
+		MatchParam("type", "fills").
+		Reply(http.StatusOK).
+		BodyString("["+reportReadyJSON+"]").
+		SetHeader("CB-AFTER", "10")
+	gock.New(gdax.EndPoint).
+		Get("/reports").
+		MatchParam("type", "fills").
+		MatchParam("after", "10").
+		Reply(http.StatusOK).
+		BodyString("[]")
+
+	reports, err := accessInfo.GetReports(gdax.Fills).Collect(context.Background())
+	assert.NoError(err)
+	assert.Len(reports, 1)
+	assert.Equal(reports[0].ID.String(), reportID)
+	assert.Equal(reports[0].Status, gdax.Ready)
+}
@@ -1,19 +1,16 @@
 package gdax
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
-	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	ws "github.com/gorilla/websocket"
@@ -23,26 +20,33 @@ import (
 const EndPoint = "https://api-public.sandbox.gdax.com"
 
 // An AccessInfo stores credentials.
+// If a CredentialProvider is set with WithCredentials, it takes precedence over PublicKey, PrivateKey, and Passphrase.
 type AccessInfo struct {
 	PublicKey  string `json:"public_api"`
 	PrivateKey string `json:"private_api"`
 	Passphrase string `json:"passphrase"`
 	Client     *http.Client
+
+	userAgent    string
+	headers      http.Header
+	log          Logger
+	redactBodies bool
+	clock        *serverClock
+	credentials  CredentialProvider
+	signer       Signer
+	hmacSigners  hmacSignerCache
+	endPoint     string
 }
 
 // RetrieveAccessInfoFromEnvironmentVariables retrieves credentials from environment variables.
-func RetrieveAccessInfoFromEnvironmentVariables() (*AccessInfo, error) {
-	var accessInfo AccessInfo
-
-	accessInfo.PublicKey = os.Getenv("PUBLIC_KEY")
-	accessInfo.PrivateKey = os.Getenv("PRIVATE_KEY")
-	accessInfo.Passphrase = os.Getenv("PASSPHRASE")
-	accessInfo.Client = &http.Client{}
-	return &accessInfo, nil
+// The HTTP client is configured by the specified options.
+func RetrieveAccessInfoFromEnvironmentVariables(options ...ClientOption) (*AccessInfo, error) {
+	return NewAccessInfo(os.Getenv("PUBLIC_KEY"), os.Getenv("PRIVATE_KEY"), os.Getenv("PASSPHRASE"), options...)
 }
 
 // RetrieveAccessInfoFromFile retrieves credentials from a specified file.
-func RetrieveAccessInfoFromFile(fileName string) (*AccessInfo, error) {
+// The HTTP client is configured by the specified options.
+func RetrieveAccessInfoFromFile(fileName string, options ...ClientOption) (*AccessInfo, error) {
 	var accessInfo AccessInfo
 
 	fileData, err := ioutil.ReadFile(fileName)
@@ -54,109 +58,108 @@ func RetrieveAccessInfoFromFile(fileName string) (*AccessInfo, error) {
 	if err != nil {
 		return nil, err
 	}
-	accessInfo.Client = &http.Client{}
+	if err := accessInfo.configure(options); err != nil {
+		return nil, err
+	}
 	return &accessInfo, nil
 }
 
 // collectionRequest is a creates and handles a request and its cursors.
-func (accessInfo *AccessInfo) collectionRequest(method, path, jsonBody string) (string, *pagination, error) {
+func (accessInfo *AccessInfo) collectionRequest(ctx context.Context, method, path, jsonBody string) ([]byte, *pagination, error) {
 	var errorMessage map[string]string
 
-	req, err := accessInfo.createRequest(method, path, jsonBody)
+	accessInfo.refreshClock(ctx)
+	req, err := accessInfo.createRequest(ctx, method, path, jsonBody)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 
-	resp, err := accessInfo.Client.Do(req)
+	logger := accessInfo.logger()
+	if logger.Enabled(ctx, LevelDebug) {
+		logger.Log(ctx, LevelDebug, "sending request",
+			"method", method, "path", path, "headers", redactHeaders(req.Header), "body", accessInfo.logBody(jsonBody))
+	}
+	start := time.Now()
+	resp, err := accessInfo.Client.Do(req.WithContext(ctx))
 	if err != nil {
-		return "", nil, err
+		logger.Log(ctx, LevelError, "request failed", "method", method, "path", path, "error", err)
+		return nil, nil, err
 	}
+	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", nil, err
+		logger.Log(ctx, LevelError, "reading response failed", "method", method, "path", path, "error", err)
+		return nil, nil, err
+	}
+	if logger.Enabled(ctx, LevelDebug) {
+		logger.Log(ctx, LevelDebug, "received response",
+			"method", method, "path", path, "status", resp.StatusCode, "duration", time.Since(start), "body", accessInfo.logBody(string(body)))
 	}
 	if !(http.StatusOK <= resp.StatusCode && resp.StatusCode < http.StatusMultipleChoices) {
+		logger.Log(ctx, LevelWarn, "request rejected", "method", method, "path", path, "status", resp.StatusCode)
 		err = json.Unmarshal(body, &errorMessage)
 		if err != nil {
-			return "", nil, err
+			return nil, nil, err
 		}
-		return "", nil, errors.New(errorMessage["message"])
+		return nil, nil, errors.New(errorMessage["message"])
 	}
 
 	cursor := pagination{
-		after: resp.Header.Get("CB-AFTER"),
-		limit: -1,
+		before: resp.Header.Get("CB-BEFORE"),
+		after:  resp.Header.Get("CB-AFTER"),
+		limit:  -1,
 	}
-	return string(body), &cursor, nil
+	return body, &cursor, nil
 }
 
 // request creates and handles a request and parses the marshals the json body response into the specified struct.
 func (accessInfo *AccessInfo) request(method, path, jsonBody string, v interface{}) (*pagination, error) {
-	var errorMessage map[string]string
-
-	req, err := accessInfo.createRequest(method, path, jsonBody)
-	if err != nil {
-		return nil, err
-	}
-
-	log.Println("created req", jsonBody)
-	resp, err := accessInfo.Client.Do(req)
-	log.Printf("resp: %+v\n", resp)
-	if err != nil {
-		return nil, err
-	}
+	return accessInfo.requestWithContext(context.Background(), method, path, jsonBody, v)
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+// requestWithContext is like request, but the request is canceled once the context is done.
+func (accessInfo *AccessInfo) requestWithContext(ctx context.Context, method, path, jsonBody string, v interface{}) (*pagination, error) {
+	body, cursor, err := accessInfo.collectionRequest(ctx, method, path, jsonBody)
 	if err != nil {
 		return nil, err
 	}
-	if !(http.StatusOK <= resp.StatusCode && resp.StatusCode < http.StatusMultipleChoices) {
-		err = json.Unmarshal(body, &errorMessage)
-		if err != nil {
-			return nil, err
-		}
-		return nil, errors.New(errorMessage["message"])
-	}
-
-	cursor := pagination{
-		after: resp.Header.Get("CB-AFTER"),
-		limit: -1,
-	}
 	err = json.Unmarshal(body, &v)
 	if err != nil {
 		return nil, err
 	}
-	return &cursor, nil
+	return cursor, nil
 }
 
 // createRequest builds, creates, and sends an HTTP request.
-func (accessInfo *AccessInfo) createRequest(method, requestPath, body string) (*http.Request, error) {
+func (accessInfo *AccessInfo) createRequest(ctx context.Context, method, requestPath, body string) (*http.Request, error) {
 	// https://docs.gdax.com/#signing-a-message
 
-	// get ISO 8601 formatted timestamp.
-	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
-
-	fullRequestPath := EndPoint + requestPath
-	// create prehash string
-	prehash := timestamp + method + requestPath + body
-	privateKeyDecoded, err := base64.StdEncoding.DecodeString(accessInfo.PrivateKey)
+	credentials, err := accessInfo.retrieveCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(accessInfo.now().Unix(), 10)
+	fullRequestPath := accessInfo.baseURL() + requestPath
+	accessSign, err := accessInfo.signerFor(credentials).Sign(timestamp, method, requestPath, body)
 	if err != nil {
 		return nil, err
 	}
-	mac := hmac.New(sha256.New, privateKeyDecoded)
-	mac.Write([]byte(prehash))
-	hashSum := mac.Sum(nil)
-	accessSign := base64.StdEncoding.EncodeToString(hashSum)
 
 	req, err := http.NewRequest(method, fullRequestPath, strings.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("CB-ACCESS-KEY", accessInfo.PublicKey)
+	for key, values := range accessInfo.headers {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	if accessInfo.userAgent != "" {
+		req.Header.Set("User-Agent", accessInfo.userAgent)
+	}
+	req.Header.Set("CB-ACCESS-KEY", credentials.PublicKey)
 	req.Header.Set("CB-ACCESS-SIGN", accessSign)
 	req.Header.Set("CB-ACCESS-TIMESTAMP", timestamp)
-	req.Header.Set("CB-ACCESS-PASSPHRASE", accessInfo.Passphrase)
+	req.Header.Set("CB-ACCESS-PASSPHRASE", credentials.Passphrase)
 	req.Header.Set("Content-Type", "application/json")
 	req.Method = method
 	url, err := url.Parse(fullRequestPath)
@@ -164,48 +167,97 @@ func (accessInfo *AccessInfo) createRequest(method, requestPath, body string) (*
 		return nil, err
 	}
 	req.URL = url
-	log.Printf("req: %+v\n", req)
 	return req, nil
 }
 
-// createWebsocketConnection creates a websocket connection.
-// This function does not block; this function creates a go routine.
-func createWebsocketConnection(addr string, initialMessage []byte, messageType chan string, jsonString chan []byte, errorChan chan error) error {
-	var wsDialer ws.Dialer
-	conn, _, err := wsDialer.Dial(addr, nil)
+// AuthenticateSubscription signs a subscription so that it also receives the messages of the user's own orders
+// (e.g., on the user channel). The signature expires, so a subscription must be signed again before it is resent.
+// If clock synchronization is enabled, the timestamp is corrected for the skew between the clocks.
+func (accessInfo *AccessInfo) AuthenticateSubscription(s *Subscription) error {
+	ctx := context.Background()
+	accessInfo.refreshClock(ctx)
+	credentials, err := accessInfo.retrieveCredentials(ctx)
 	if err != nil {
 		return err
 	}
-	var m map[string]interface{}
-	if err := json.Unmarshal(initialMessage, &m); err != nil {
+	timestamp := strconv.FormatInt(accessInfo.now().Unix(), 10)
+	signature, err := accessInfo.signerFor(credentials).Sign(timestamp, http.MethodGet, "/users/self/verify", "")
+	if err != nil {
 		return err
 	}
-	if err := conn.WriteJSON(m); err != nil {
-		return err
+	s.Signature = signature
+	s.Key = credentials.PublicKey
+	s.Passphrase = credentials.Passphrase
+	s.Timestamp = timestamp
+	return nil
+}
+
+// A feedConnection reads and decodes the frames of a single websocket connection.
+type feedConnection struct {
+	conn        *ws.Conn
+	readTimeout time.Duration
+	writeMu     sync.Mutex
+	closeOnce   sync.Once
+	closeErr    error
+}
+
+// dialFeed creates a websocket connection and sends the specified subscription.
+func dialFeed(ctx context.Context, endpoint string, s *Subscription) (*feedConnection, error) {
+	var wsDialer ws.Dialer
+	conn, _, err := wsDialer.DialContext(ctx, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.WriteJSON(s); err != nil {
+		conn.Close()
+		return nil, err
 	}
-	go func() {
-		for {
-			var v []byte
-			var q map[string]interface{}
-			_, v, err := conn.ReadMessage()
-			if err != nil {
-				errorChan <- err
-				break
-			}
-			if err := json.Unmarshal(v, &q); err != nil {
-				errorChan <- err
-				break
-			}
-			errorChan <- nil
-			if t, ok := q["type"]; ok {
-				z := reflect.ValueOf(t).Convert(reflect.TypeOf(string(v))).Interface().(string)
-				messageType <- z
-				jsonString <- v
-				if z == "error" {
-					break
-				}
-			}
+	return &feedConnection{conn: conn}, nil
+}
+
+// readFrame blocks until the next frame arrives.
+// If the connection has a read timeout, the frame (or a pong) must arrive before the timeout.
+func (c *feedConnection) readFrame() ([]byte, error) {
+	if c.readTimeout > 0 {
+		if err := c.conn.SetReadDeadline(time.Now().Add(c.readTimeout)); err != nil {
+			return nil, err
 		}
-	}()
-	return nil
+	}
+	_, frame, err := c.conn.ReadMessage()
+	return frame, err
+}
+
+// setReadTimeout sets how long the connection waits for a frame or a pong before failing.
+func (c *feedConnection) setReadTimeout(readTimeout time.Duration) {
+	c.readTimeout = readTimeout
+	if readTimeout <= 0 {
+		return
+	}
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+}
+
+// subscribe sends a subscription over the open connection, which must be written within the specified timeout.
+func (c *feedConnection) subscribe(s *Subscription, writeTimeout time.Duration) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	return c.conn.WriteJSON(s)
+}
+
+// ping sends a websocket ping, which must be written within the specified timeout.
+func (c *feedConnection) ping(writeTimeout time.Duration) error {
+	return c.conn.WriteControl(ws.PingMessage, nil, time.Now().Add(writeTimeout))
+}
+
+// Close closes the connection, which unblocks any pending call to readFrame.
+// It is safe to call Close more than once.
+func (c *feedConnection) Close() error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.conn.Close()
+	})
+	return c.closeErr
 }
@@ -0,0 +1,293 @@
+package gdax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// A ShardedFeedStream splits the products of a subscription across several connections and merges their messages.
+// Every product is carried by exactly one connection at a time, so the messages of each product are delivered in the
+// order they were sent. When a connection drops, its products are resubscribed on the remaining connections.
+type ShardedFeedStream struct {
+	messages   chan Message
+	errors     chan error
+	cancel     context.CancelFunc
+	done       chan struct{}
+	onLiveness func(LivenessEvent)
+	streams    []*FeedStream
+
+	mu         sync.Mutex
+	assigned   map[*FeedStream][]string
+	owners     map[string]*FeedStream
+	rebalances uint64
+	err        error
+}
+
+// ShardedStream makes a subscription to the specified channel over at most the specified number of connections and
+// sends any incoming messages to the returned ShardedFeedStream. The options apply to each connection.
+// This function does not block; the ShardedFeedStream is closed once the context is done or every connection has dropped.
+func ShardedStream(ctx context.Context, s *Subscription, shards int, options *StreamOptions) (*ShardedFeedStream, error) {
+	if shards <= 0 {
+		return nil, errors.New("the number of shards must be positive")
+	}
+	if len(s.ProductIDs) == 0 {
+		return nil, errors.New("a sharded stream needs at least one product")
+	}
+	if shards > len(s.ProductIDs) {
+		shards = len(s.ProductIDs)
+	}
+	if options == nil {
+		options = &StreamOptions{}
+	}
+	bufferSize := options.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = DefaultStreamBufferSize
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	sharded := ShardedFeedStream{
+		messages:   make(chan Message, bufferSize),
+		errors:     make(chan error, 1),
+		cancel:     cancel,
+		done:       make(chan struct{}),
+		onLiveness: options.OnLiveness,
+		assigned:   make(map[*FeedStream][]string, shards),
+		owners:     make(map[string]*FeedStream, len(s.ProductIDs)),
+	}
+	for _, productIDs := range splitProducts(s.ProductIDs, shards) {
+		subscription := *s
+		subscription.ProductIDs = productIDs
+		stream, err := Stream(ctx, &subscription, options)
+		if err != nil {
+			cancel()
+			for _, stream := range sharded.streams {
+				stream.Close()
+			}
+			return nil, err
+		}
+		sharded.streams = append(sharded.streams, stream)
+		sharded.assigned[stream] = productIDs
+		for _, productID := range productIDs {
+			sharded.owners[productID] = stream
+		}
+	}
+
+	var forwarders sync.WaitGroup
+	for _, stream := range sharded.streams {
+		forwarders.Add(1)
+		go func(stream *FeedStream) {
+			defer forwarders.Done()
+			sharded.forward(ctx, stream)
+		}(stream)
+	}
+	go func() {
+		defer close(sharded.done)
+		defer close(sharded.errors)
+		defer close(sharded.messages)
+		defer cancel()
+		forwarders.Wait()
+		sharded.mu.Lock()
+		err := sharded.err
+		sharded.mu.Unlock()
+		if err == nil {
+			err = ctx.Err()
+		}
+		sharded.errors <- err
+	}()
+	return &sharded, nil
+}
+
+// Messages gets the channel of incoming messages of every connection.
+// The channel is closed when the stream terminates.
+func (sharded *ShardedFeedStream) Messages() <-chan Message {
+	return sharded.messages
+}
+
+// Errors gets the channel that receives the error that terminated the stream.
+// If every connection dropped, the error is the one that dropped the last connection, and if the products of a dropped
+// connection could not be subscribed to on any other connection, the error is the one that prevented the move.
+// The channel is closed after at most one error is sent.
+func (sharded *ShardedFeedStream) Errors() <-chan error {
+	return sharded.errors
+}
+
+// Received gets the number of messages received from every connection.
+func (sharded *ShardedFeedStream) Received() uint64 {
+	var received uint64
+	for _, stream := range sharded.streams {
+		received += stream.Received()
+	}
+	return received
+}
+
+// Dropped gets the number of messages dropped by every connection because its buffer was full.
+func (sharded *ShardedFeedStream) Dropped() uint64 {
+	var dropped uint64
+	for _, stream := range sharded.streams {
+		dropped += stream.Dropped()
+	}
+	return dropped
+}
+
+// Shards gets the number of connections that are still open.
+func (sharded *ShardedFeedStream) Shards() int {
+	sharded.mu.Lock()
+	defer sharded.mu.Unlock()
+	return len(sharded.assigned)
+}
+
+// Rebalances gets the number of times the products of a dropped connection were moved to the remaining connections.
+func (sharded *ShardedFeedStream) Rebalances() uint64 {
+	sharded.mu.Lock()
+	defer sharded.mu.Unlock()
+	return sharded.rebalances
+}
+
+// Status gets the liveness state of the specified product from the connection that carries it.
+func (sharded *ShardedFeedStream) Status(productID string) (ProductStatus, bool) {
+	sharded.mu.Lock()
+	stream, ok := sharded.owners[productID]
+	sharded.mu.Unlock()
+	if !ok {
+		return ProductStatus{}, false
+	}
+	return stream.Status(productID)
+}
+
+// Close terminates every connection and waits for them to shut down.
+// Messages that were already buffered can still be received after Close returns.
+func (sharded *ShardedFeedStream) Close() {
+	sharded.cancel()
+	<-sharded.done
+}
+
+// forward sends the messages of a single connection to the merged stream until the connection terminates,
+// then moves its products to the remaining connections.
+// Since every buffered message is forwarded before the products are moved, the order of each product is preserved.
+func (sharded *ShardedFeedStream) forward(ctx context.Context, stream *FeedStream) {
+	defer stream.Close()
+	for message := range stream.Messages() {
+		select {
+		case sharded.messages <- message:
+		case <-ctx.Done():
+			return
+		}
+	}
+	err := <-stream.Errors()
+	if ctx.Err() != nil {
+		return
+	}
+	sharded.rebalance(stream, err)
+}
+
+// rebalance moves the products of a dropped connection to the remaining connections with the fewest products.
+// If no connections remain, the stream terminates with the error that dropped the connection.
+// If products cannot be subscribed to on a connection, the move is rolled back and the products are moved to the
+// other connections; if no connection accepts them, the stream terminates with the error.
+func (sharded *ShardedFeedStream) rebalance(dropped *FeedStream, err error) {
+	sharded.mu.Lock()
+	productIDs := sharded.assigned[dropped]
+	delete(sharded.assigned, dropped)
+	if len(sharded.assigned) == 0 {
+		sharded.err = err
+		sharded.mu.Unlock()
+		return
+	}
+	sharded.rebalances++
+	sharded.mu.Unlock()
+
+	failed := make(map[*FeedStream]bool)
+	for len(productIDs) > 0 {
+		moves := sharded.assign(productIDs, failed)
+		if moves == nil {
+			sharded.mu.Lock()
+			for _, productID := range productIDs {
+				delete(sharded.owners, productID)
+			}
+			if sharded.err == nil {
+				sharded.err = fmt.Errorf("could not move %v off of a dropped connection: %w", productIDs, err)
+			}
+			sharded.mu.Unlock()
+			sharded.cancel()
+			return
+		}
+
+		productIDs = nil
+		now := time.Now()
+		for _, stream := range sharded.streams {
+			moved, ok := moves[stream]
+			if !ok {
+				continue
+			}
+			// if the target has also dropped, its own forwarder moves these products again.
+			if subscribeErr := stream.Subscribe(moved...); subscribeErr != nil {
+				stream.forget(moved)
+				failed[stream] = true
+				err = subscribeErr
+				if sharded.unassign(stream, moved) {
+					productIDs = append(productIDs, moved...)
+				}
+				continue
+			}
+			if sharded.onLiveness != nil {
+				for _, productID := range moved {
+					sharded.onLiveness(LivenessEvent{Kind: Rebalanced, ProductID: productID, Time: now})
+				}
+			}
+		}
+	}
+}
+
+// assign assigns products to the remaining connections with the fewest products, except the excluded connections.
+// It returns the products assigned to each connection, or nil if no connection can take them.
+func (sharded *ShardedFeedStream) assign(productIDs []string, excluded map[*FeedStream]bool) map[*FeedStream][]string {
+	sharded.mu.Lock()
+	defer sharded.mu.Unlock()
+	moves := make(map[*FeedStream][]string)
+	for _, productID := range productIDs {
+		var target *FeedStream
+		for _, stream := range sharded.streams {
+			if products, ok := sharded.assigned[stream]; ok && !excluded[stream] && (target == nil || len(products) < len(sharded.assigned[target])) {
+				target = stream
+			}
+		}
+		if target == nil {
+			return nil
+		}
+		sharded.assigned[target] = append(sharded.assigned[target], productID)
+		sharded.owners[productID] = target
+		moves[target] = append(moves[target], productID)
+	}
+	return moves
+}
+
+// unassign rolls back the assignment of products to a connection.
+// It returns false if the connection has dropped in the meantime, in which case its forwarder moves the products.
+func (sharded *ShardedFeedStream) unassign(stream *FeedStream, productIDs []string) bool {
+	sharded.mu.Lock()
+	defer sharded.mu.Unlock()
+	if _, ok := sharded.assigned[stream]; !ok {
+		return false
+	}
+	remaining := make([]string, 0, len(sharded.assigned[stream]))
+	for _, productID := range sharded.assigned[stream] {
+		if !slices.Contains(productIDs, productID) {
+			remaining = append(remaining, productID)
+		}
+	}
+	sharded.assigned[stream] = remaining
+	return true
+}
+
+// splitProducts splits productIDs into the specified number of groups of nearly equal size.
+func splitProducts(productIDs []string, groups int) [][]string {
+	split := make([][]string, groups)
+	for idx, productID := range productIDs {
+		split[idx%groups] = append(split[idx%groups], productID)
+	}
+	return split
+}
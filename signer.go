@@ -0,0 +1,85 @@
+package gdax
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"sync"
+)
+
+// A Signer creates the CB-ACCESS-SIGN signature of a request (or of an authenticated subscription, which is signed
+// as GET /users/self/verify with an empty body).
+// Implementations can keep the secret outside of the process (e.g., in an HSM or a remote signing service).
+type Signer interface {
+	Sign(timestamp, method, requestPath, body string) (string, error)
+}
+
+// An HMACSigner signs requests in process with the base64-encoded API secret. It is the default Signer.
+type HMACSigner struct {
+	secret []byte
+}
+
+// NewHMACSigner creates an HMACSigner from the base64-encoded API secret.
+func NewHMACSigner(privateKey string) (*HMACSigner, error) {
+	secret, err := base64.StdEncoding.DecodeString(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &HMACSigner{secret: secret}, nil
+}
+
+// Sign creates the base64-encoded HMAC-SHA256 of the prehash string (i.e., timestamp + method + requestPath + body).
+func (signer *HMACSigner) Sign(timestamp, method, requestPath, body string) (string, error) {
+	// create prehash string
+	prehash := timestamp + method + requestPath + body
+	mac := hmac.New(sha256.New, signer.secret)
+	mac.Write([]byte(prehash))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// A failedSigner returns the error that prevented a Signer from being created.
+type failedSigner struct {
+	err error
+}
+
+// Sign returns the error.
+func (signer failedSigner) Sign(string, string, string, string) (string, error) {
+	return "", signer.err
+}
+
+// WithSigner signs every request and subscription with the specified Signer instead of the private key.
+// The public key and passphrase are still taken from the credentials.
+func WithSigner(signer Signer) ClientOption {
+	return func(config *clientConfig) error {
+		config.signer = signer
+		return nil
+	}
+}
+
+// An hmacSignerCache holds the default Signer of the last private key that signed a request, so that the key is only
+// decoded again when the credentials are rotated.
+type hmacSignerCache struct {
+	mu         sync.Mutex
+	privateKey string
+	signer     Signer
+}
+
+// signerFor gets the Signer of an AccessInfo, which defaults to an HMACSigner of the specified credentials.
+func (accessInfo *AccessInfo) signerFor(credentials Credentials) Signer {
+	if accessInfo.signer != nil {
+		return accessInfo.signer
+	}
+	cache := &accessInfo.hmacSigners
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.signer == nil || cache.privateKey != credentials.PrivateKey {
+		signer, err := NewHMACSigner(credentials.PrivateKey)
+		if err != nil {
+			cache.signer = failedSigner{err: err}
+		} else {
+			cache.signer = signer
+		}
+		cache.privateKey = credentials.PrivateKey
+	}
+	return cache.signer
+}
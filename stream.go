@@ -0,0 +1,414 @@
+package gdax
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Overflow Policies
+const (
+	Block      = "block"
+	DropOldest = "drop_oldest"
+	Disconnect = "disconnect"
+)
+
+// DefaultStreamBufferSize is the number of messages buffered by a FeedStream if no buffer size is specified.
+const DefaultStreamBufferSize = 1024
+
+// Stream timeouts
+const (
+	defaultWriteTimeout  = 10 * time.Second
+	minReconnectInterval = 500 * time.Millisecond
+	maxReconnectInterval = 30 * time.Second
+)
+
+// ErrStreamOverflow is sent by a FeedStream with the Disconnect overflow policy when its buffer is full.
+var ErrStreamOverflow = errors.New("feed stream buffer overflowed")
+
+// StreamOptions configure a FeedStream.
+type StreamOptions struct {
+	// BufferSize is the number of messages buffered before the overflow policy applies.
+	// If zero, DefaultStreamBufferSize is used.
+	BufferSize int
+	// OverflowPolicy is what happens when a message arrives while the buffer is full:
+	// Block (the default) stops reading from the socket until there is room,
+	// DropOldest discards the oldest buffered message, and Disconnect closes the stream with ErrStreamOverflow.
+	OverflowPolicy string
+	// EndPoint is the websocket endpoint to connect to. If empty, FeedEndPoint is used.
+	EndPoint string
+	// AccessInfo, if set, authenticates the subscription every time it is sent (including after reconnecting),
+	// so that the stream also receives the messages of the user's own orders.
+	AccessInfo *AccessInfo
+	// Recorder, if set, records every raw frame as it is received.
+	// If a frame cannot be recorded, the stream terminates with the error.
+	Recorder *Recorder
+
+	// Heartbeats subscribes to the heartbeat channel for every product, even if it is not in the subscription.
+	Heartbeats bool
+	// StaleAfter is how long a product may go without any message before it is reported as Stale.
+	// If zero, staleness is not checked. With Heartbeats, one second is the shortest sensible value.
+	StaleAfter time.Duration
+	// OnLiveness is called when a product goes stale, a trade or sequence gap is detected, or the stream reconnects.
+	// It is called from the stream's goroutines, so it must not block.
+	OnLiveness func(LivenessEvent)
+	// Reconnect reconnects and resubscribes when a product goes stale, a ping is not answered, or the connection drops,
+	// instead of closing the stream.
+	Reconnect bool
+
+	// PingInterval is how often a websocket ping is sent. If zero, no pings are sent.
+	PingInterval time.Duration
+	// PongTimeout is how long the connection may go without a frame or a pong before it is considered dead.
+	// If zero, reads never time out.
+	PongTimeout time.Duration
+	// WriteTimeout is how long a ping may take to be written. If zero, ten seconds is used.
+	WriteTimeout time.Duration
+}
+
+// A FeedStream delivers the messages of a subscription on a channel.
+// Unlike Feed, a slow consumer of a FeedStream does not stall the socket unless the Block overflow policy is used.
+type FeedStream struct {
+	messages     chan Message
+	errors       chan error
+	received     uint64
+	dropped      uint64
+	reconnects   uint64
+	cancel       context.CancelFunc
+	done         chan struct{}
+	subscription Subscription
+	options      StreamOptions
+	monitor      *livenessMonitor
+
+	// subscribeMu serializes dialing and subscribing, so that products added by Subscribe are never missed by a new connection.
+	subscribeMu sync.Mutex
+	mu          sync.Mutex
+	conn        *feedConnection
+	staleConn   *feedConnection
+}
+
+// Stream makes a subscription to the specified channel and sends any incoming messages to the returned FeedStream.
+// This function does not block; the FeedStream is closed once the context is done, the connection is dropped/terminated
+// (unless Reconnect is set), an error is sent, or the buffer overflows with the Disconnect overflow policy.
+func Stream(ctx context.Context, s *Subscription, options *StreamOptions) (*FeedStream, error) {
+	stream := FeedStream{subscription: *s}
+	if options != nil {
+		stream.options = *options
+	}
+	if stream.options.BufferSize <= 0 {
+		stream.options.BufferSize = DefaultStreamBufferSize
+	}
+	switch stream.options.OverflowPolicy {
+	case "":
+		stream.options.OverflowPolicy = Block
+	case Block, DropOldest, Disconnect:
+	default:
+		return nil, errors.New("unknown overflow policy: " + stream.options.OverflowPolicy)
+	}
+	if stream.options.EndPoint == "" {
+		stream.options.EndPoint = FeedEndPoint
+	}
+	if stream.options.WriteTimeout <= 0 {
+		stream.options.WriteTimeout = defaultWriteTimeout
+	}
+	if stream.options.Heartbeats {
+		stream.subscription.Channels = withChannel(s.Channels, HeartbeatType)
+	}
+	stream.monitor = newLivenessMonitor(&stream.subscription, stream.options.StaleAfter, stream.options.OnLiveness)
+
+	conn, err := stream.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream.messages = make(chan Message, stream.options.BufferSize)
+	stream.errors = make(chan error, 1)
+	stream.cancel = cancel
+	stream.done = make(chan struct{})
+	// closing the connection is the only way to unblock a pending read.
+	stopClosing := context.AfterFunc(ctx, stream.closeConnection)
+	watchdogDone := make(chan struct{})
+	go func() {
+		defer close(watchdogDone)
+		stream.watchdog(ctx)
+	}()
+	go func() {
+		defer close(stream.done)
+		defer close(stream.errors)
+		defer close(stream.messages)
+		defer func() { <-watchdogDone }()
+		defer cancel()
+		defer stream.closeConnection()
+		defer stopClosing()
+		stream.errors <- stream.run(ctx, conn)
+	}()
+	return &stream, nil
+}
+
+// Messages gets the channel of incoming messages.
+// The channel is closed when the stream terminates.
+func (stream *FeedStream) Messages() <-chan Message {
+	return stream.messages
+}
+
+// Errors gets the channel that receives the error that terminated the stream.
+// The channel is closed after at most one error is sent.
+func (stream *FeedStream) Errors() <-chan error {
+	return stream.errors
+}
+
+// Received gets the number of messages received from the socket.
+func (stream *FeedStream) Received() uint64 {
+	return atomic.LoadUint64(&stream.received)
+}
+
+// Dropped gets the number of messages dropped because the buffer was full.
+func (stream *FeedStream) Dropped() uint64 {
+	return atomic.LoadUint64(&stream.dropped)
+}
+
+// Reconnects gets the number of times the stream has reconnected.
+func (stream *FeedStream) Reconnects() uint64 {
+	return atomic.LoadUint64(&stream.reconnects)
+}
+
+// Status gets the liveness state of the specified product.
+func (stream *FeedStream) Status(productID string) (ProductStatus, bool) {
+	return stream.monitor.status(productID)
+}
+
+// Close terminates the stream and waits for its connection to shut down.
+// Messages that were already buffered can still be received after Close returns.
+func (stream *FeedStream) Close() {
+	stream.cancel()
+	<-stream.done
+}
+
+// Subscribe adds the specified products to the stream's subscription, on the same channels.
+// If the subscription cannot be sent, the products are still subscribed to if the stream reconnects.
+func (stream *FeedStream) Subscribe(productIDs ...string) error {
+	stream.subscribeMu.Lock()
+	defer stream.subscribeMu.Unlock()
+	stream.mu.Lock()
+	conn := stream.conn
+	stream.subscription.ProductIDs = append(append([]string(nil), stream.subscription.ProductIDs...), productIDs...)
+	stream.mu.Unlock()
+	stream.monitor.add(productIDs, time.Now())
+	subscription := Subscription{
+		Type:       SubscribeType,
+		Channels:   stream.subscription.Channels,
+		ProductIDs: productIDs,
+	}
+	if stream.options.AccessInfo != nil {
+		if err := stream.options.AccessInfo.AuthenticateSubscription(&subscription); err != nil {
+			return err
+		}
+	}
+	return conn.subscribe(&subscription, stream.options.WriteTimeout)
+}
+
+// forget removes the specified products from the stream's subscription without unsubscribing from them, so that they
+// are not subscribed to again if the stream reconnects.
+func (stream *FeedStream) forget(productIDs []string) {
+	stream.subscribeMu.Lock()
+	defer stream.subscribeMu.Unlock()
+	stream.mu.Lock()
+	remaining := make([]string, 0, len(stream.subscription.ProductIDs))
+	for _, productID := range stream.subscription.ProductIDs {
+		if !slices.Contains(productIDs, productID) {
+			remaining = append(remaining, productID)
+		}
+	}
+	stream.subscription.ProductIDs = remaining
+	stream.mu.Unlock()
+	stream.monitor.remove(productIDs)
+}
+
+// dial creates a connection for the stream's subscription and makes it the current connection.
+func (stream *FeedStream) dial(ctx context.Context) (*feedConnection, error) {
+	stream.subscribeMu.Lock()
+	defer stream.subscribeMu.Unlock()
+	stream.mu.Lock()
+	subscription := stream.subscription
+	stream.mu.Unlock()
+	if stream.options.AccessInfo != nil {
+		if err := stream.options.AccessInfo.AuthenticateSubscription(&subscription); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := dialFeed(ctx, stream.options.EndPoint, &subscription)
+	if err != nil {
+		return nil, err
+	}
+	conn.setReadTimeout(stream.options.PongTimeout)
+	stream.mu.Lock()
+	stream.conn = conn
+	stream.mu.Unlock()
+	return conn, nil
+}
+
+// redial reconnects with exponential backoff until it succeeds or the context is done.
+func (stream *FeedStream) redial(ctx context.Context) (*feedConnection, error) {
+	interval := minReconnectInterval
+	for {
+		conn, err := stream.dial(ctx)
+		if err == nil {
+			atomic.AddUint64(&stream.reconnects, 1)
+			stream.monitor.reconnected(time.Now())
+			return conn, nil
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if interval *= 2; interval > maxReconnectInterval {
+			interval = maxReconnectInterval
+		}
+	}
+}
+
+// currentConnection gets the connection that the stream is currently reading from.
+func (stream *FeedStream) currentConnection() *feedConnection {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	return stream.conn
+}
+
+// closeConnection closes the current connection, which unblocks any pending read.
+func (stream *FeedStream) closeConnection() {
+	if conn := stream.currentConnection(); conn != nil {
+		conn.Close()
+	}
+}
+
+// run reads messages into the buffer until an error occurs, reconnecting if the stream is configured to.
+func (stream *FeedStream) run(ctx context.Context, conn *feedConnection) error {
+	for {
+		reconnectable, err := stream.read(ctx, conn)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		stream.mu.Lock()
+		wentStale := stream.staleConn == conn
+		stream.mu.Unlock()
+		if !stream.options.Reconnect || !(reconnectable || wentStale) {
+			return err
+		}
+		conn.Close()
+		if conn, err = stream.redial(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// read reads messages from a single connection into the buffer until an error occurs.
+// The returned boolean is true if the error came from the connection itself.
+func (stream *FeedStream) read(ctx context.Context, conn *feedConnection) (bool, error) {
+	for {
+		frame, err := conn.readFrame()
+		if err != nil {
+			return true, err
+		}
+		if stream.options.Recorder != nil {
+			if err := stream.options.Recorder.Record(frame, time.Now()); err != nil {
+				return false, err
+			}
+		}
+		message, err := decodeFrame(frame)
+		if err != nil {
+			return false, err
+		}
+		atomic.AddUint64(&stream.received, 1)
+		stream.monitor.observe(message, time.Now())
+		if err := stream.deliver(ctx, message); err != nil {
+			return false, err
+		}
+		if e, ok := message.(Error); ok {
+			return false, e
+		}
+	}
+}
+
+// watchdog pings the connection and checks for stale products until the context is done.
+func (stream *FeedStream) watchdog(ctx context.Context) {
+	var pings, staleChecks <-chan time.Time
+	if stream.options.PingInterval > 0 {
+		ticker := time.NewTicker(stream.options.PingInterval)
+		defer ticker.Stop()
+		pings = ticker.C
+	}
+	if stream.options.StaleAfter > 0 {
+		ticker := time.NewTicker(stream.options.StaleAfter / 4)
+		defer ticker.Stop()
+		staleChecks = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-pings:
+			conn := stream.currentConnection()
+			if err := conn.ping(stream.options.WriteTimeout); err != nil {
+				conn.Close()
+			}
+		case now := <-staleChecks:
+			if stream.monitor.checkStale(now) && stream.options.Reconnect {
+				stream.mu.Lock()
+				conn := stream.conn
+				stream.staleConn = conn
+				stream.mu.Unlock()
+				conn.Close()
+			}
+		}
+	}
+}
+
+// deliver sends a message to the stream's buffer according to its overflow policy.
+func (stream *FeedStream) deliver(ctx context.Context, message Message) error {
+	switch stream.options.OverflowPolicy {
+	case DropOldest:
+		for {
+			select {
+			case stream.messages <- message:
+				return nil
+			default:
+			}
+			select {
+			case <-stream.messages:
+				atomic.AddUint64(&stream.dropped, 1)
+			default:
+			}
+		}
+	case Disconnect:
+		select {
+		case stream.messages <- message:
+			return nil
+		default:
+			atomic.AddUint64(&stream.dropped, 1)
+			return ErrStreamOverflow
+		}
+	default:
+		select {
+		case stream.messages <- message:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// withChannel adds a channel to a list of channels if it is not already present.
+func withChannel(channels []string, channel string) []string {
+	for _, c := range channels {
+		if c == channel {
+			return channels
+		}
+	}
+	return append(append([]string(nil), channels...), channel)
+}
@@ -0,0 +1,538 @@
+package gdax_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	l2updateJSON = `{"type": "l2update", "product_id": "%s", "time": "2019-08-14T20:42:27.265Z", "changes": [["buy", "10101.80000000", "0.162567"], ["sell", "10102.10000000", "0"]]}`
+	snapshotJSON = `{"type": "snapshot", "product_id": "BTC-USD", "bids": [["10101.10", "0.45054140"]], "asks": [["10102.55", "0.57753524"]]}`
+	noTypeJSON   = `{"product_id": "BTC-USD", "message": "no type"}`
+	doneJSON     = `{"type": "done", "product_id": "BTC-USD", "reason": "filled"}`
+)
+
+var feedSubscription = gdax.Subscription{
+	Type:       gdax.SubscribeType,
+	Channels:   []string{gdax.TickerType, gdax.Level2Type, gdax.MatchesType},
+	ProductIDs: []string{"BTC-USD"},
+}
+
+// newFeedServer creates a websocket server that sends the specified frames after receiving a subscription.
+// The connection is held open until the client closes it.
+func newFeedServer(tb testing.TB, frames func(conn *ws.Conn) error) (*httptest.Server, string) {
+	var upgrader ws.Upgrader
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			tb.Error(err)
+			return
+		}
+		defer conn.Close()
+		var subscription gdax.Subscription
+		if err := conn.ReadJSON(&subscription); err != nil {
+			tb.Error(err)
+			return
+		}
+		if err := frames(conn); err != nil {
+			return
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	return server, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// sendFrames creates a frames function that sends the specified frames.
+func sendFrames(frames ...string) func(conn *ws.Conn) error {
+	return func(conn *ws.Conn) error {
+		for _, frame := range frames {
+			if err := conn.WriteMessage(ws.TextMessage, []byte(frame)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestStream(t *testing.T) {
+	assert := assert.New(t)
+
+	server, endPoint := newFeedServer(t, sendFrames(
+		fmt.Sprintf(tickerJSON, "BTC-USD"),
+		fmt.Sprintf(l2updateJSON, "BTC-USD"),
+		snapshotJSON,
+		matchJSON,
+		noTypeJSON,
+		doneJSON,
+	))
+	defer server.Close()
+
+	stream, err := gdax.Stream(context.Background(), &feedSubscription, &gdax.StreamOptions{EndPoint: endPoint})
+	assert.NoError(err)
+	defer stream.Close()
+
+	var messages []gdax.Message
+	for len(messages) < 6 {
+		messages = append(messages, <-stream.Messages())
+	}
+
+	ticker := messages[0].(gdax.Ticker)
+	assert.Equal(ticker.ProductID, "BTC-USD")
+	assert.Equal(ticker.Price, 4388.01)
+
+	l2update := messages[1].(gdax.L2Update)
+	assert.Equal(l2update.ProductID, "BTC-USD")
+	assert.Equal(l2update.Changes, []gdax.Change{{Side: gdax.Buy, Price: 10101.8, Size: 0.162567}, {Side: gdax.Sell, Price: 10102.1, Size: 0}})
+
+	snapshot := messages[2].(gdax.Snapshot)
+	assert.Equal(snapshot.Bids, []gdax.Bid{{Price: 10101.10, Size: 0.45054140}})
+	assert.Equal(snapshot.Asks, []gdax.Ask{{Price: 10102.55, Size: 0.57753524}})
+
+	assert.Equal(messages[3].(gdax.Match).TradeID, int64(10))
+
+	noType := messages[4].(gdax.Unknown)
+	assert.Equal(noType.MessageType(), "")
+	assert.Equal(string(noType.Raw), noTypeJSON)
+
+	done := messages[5].(gdax.Unknown)
+	assert.Equal(done.MessageType(), "done")
+	assert.Equal(stream.Received(), uint64(6))
+}
+
+func TestStreamClose(t *testing.T) {
+	assert := assert.New(t)
+
+	server, endPoint := newFeedServer(t, sendFrames())
+	defer server.Close()
+
+	stream, err := gdax.Stream(context.Background(), &feedSubscription, &gdax.StreamOptions{EndPoint: endPoint})
+	assert.NoError(err)
+
+	closed := make(chan struct{})
+	go func() {
+		stream.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not close")
+	}
+
+	_, ok := <-stream.Messages()
+	assert.False(ok)
+	assert.Equal(<-stream.Errors(), context.Canceled)
+}
+
+func TestStreamError(t *testing.T) {
+	assert := assert.New(t)
+
+	server, endPoint := newFeedServer(t, sendFrames(`{"type": "error", "message": "Failed to subscribe"}`))
+	defer server.Close()
+
+	stream, err := gdax.Stream(context.Background(), &feedSubscription, &gdax.StreamOptions{EndPoint: endPoint})
+	assert.NoError(err)
+	defer stream.Close()
+
+	message := <-stream.Messages()
+	assert.Equal(message.MessageType(), gdax.ErrorType)
+	err = <-stream.Errors()
+	assert.Error(err)
+	assert.Equal(err.Error(), "Failed to subscribe")
+}
+
+func TestStreamDropOldest(t *testing.T) {
+	assert := assert.New(t)
+
+	var frames []string
+	for i := 0; i < 5; i++ {
+		frames = append(frames, fmt.Sprintf(tickerJSON, fmt.Sprintf("BTC-USD-%d", i)))
+	}
+	server, endPoint := newFeedServer(t, sendFrames(frames...))
+	defer server.Close()
+
+	stream, err := gdax.Stream(context.Background(), &feedSubscription, &gdax.StreamOptions{
+		EndPoint:       endPoint,
+		BufferSize:     1,
+		OverflowPolicy: gdax.DropOldest,
+	})
+	assert.NoError(err)
+	defer stream.Close()
+
+	assert.Eventually(func() bool { return stream.Received() == 5 }, 5*time.Second, time.Millisecond)
+	ticker := (<-stream.Messages()).(gdax.Ticker)
+	assert.Equal(ticker.ProductID, "BTC-USD-4")
+	assert.Equal(stream.Dropped(), uint64(4))
+}
+
+func TestStreamDisconnect(t *testing.T) {
+	assert := assert.New(t)
+
+	server, endPoint := newFeedServer(t, sendFrames(matchJSON, matchJSON, matchJSON))
+	defer server.Close()
+
+	stream, err := gdax.Stream(context.Background(), &feedSubscription, &gdax.StreamOptions{
+		EndPoint:       endPoint,
+		BufferSize:     1,
+		OverflowPolicy: gdax.Disconnect,
+	})
+	assert.NoError(err)
+	defer stream.Close()
+
+	assert.Equal(<-stream.Errors(), gdax.ErrStreamOverflow)
+	assert.Equal(stream.Dropped(), uint64(1))
+}
+
+func TestStreamUnknownOverflowPolicy(t *testing.T) {
+	_, err := gdax.Stream(context.Background(), &feedSubscription, &gdax.StreamOptions{OverflowPolicy: "spill"})
+	assert.Error(t, err)
+}
+
+// BenchmarkStream measures the throughput of a single connection carrying the busiest channels of several products.
+func BenchmarkStream(b *testing.B) {
+	products := []string{"BTC-USD", "ETH-USD", "LTC-USD", "BTC-EUR", "ETH-BTC", "BCH-USD"}
+	var frames [][]byte
+	for _, product := range products {
+		frames = append(frames,
+			[]byte(fmt.Sprintf(l2updateJSON, product)),
+			[]byte(fmt.Sprintf(l2updateJSON, product)),
+			[]byte(fmt.Sprintf(tickerJSON, product)),
+			[]byte(strings.Replace(matchJSON, "BTC-USD", product, 1)),
+		)
+	}
+
+	server, endPoint := newFeedServer(b, func(conn *ws.Conn) error {
+		for i := 0; i < b.N; i++ {
+			if err := conn.WriteMessage(ws.TextMessage, frames[i%len(frames)]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	defer server.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	stream, err := gdax.Stream(context.Background(), &feedSubscription, &gdax.StreamOptions{EndPoint: endPoint})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer stream.Close()
+	for i := 0; i < b.N; i++ {
+		if _, ok := <-stream.Messages(); !ok {
+			b.Fatal(<-stream.Errors())
+		}
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/s")
+}
+
+func TestStreamStale(t *testing.T) {
+	assert := assert.New(t)
+
+	server, endPoint := newFeedServer(t, sendFrames())
+	defer server.Close()
+
+	events := make(chan gdax.LivenessEvent, 10)
+	stream, err := gdax.Stream(context.Background(), &feedSubscription, &gdax.StreamOptions{
+		EndPoint:   endPoint,
+		Heartbeats: true,
+		StaleAfter: 50 * time.Millisecond,
+		OnLiveness: func(event gdax.LivenessEvent) { events <- event },
+	})
+	assert.NoError(err)
+	defer stream.Close()
+
+	select {
+	case event := <-events:
+		assert.Equal(event.Kind, gdax.Stale)
+		assert.Equal(event.ProductID, "BTC-USD")
+	case <-time.After(5 * time.Second):
+		t.Fatal("stale product was not reported")
+	}
+	status, ok := stream.Status("BTC-USD")
+	assert.True(ok)
+	assert.True(status.Stale)
+}
+
+func TestStreamTradeGap(t *testing.T) {
+	assert := assert.New(t)
+
+	match := func(tradeID int) string {
+		return strings.Replace(matchJSON, `"trade_id": 10`, fmt.Sprintf(`"trade_id": %d`, tradeID), 1)
+	}
+	server, endPoint := newFeedServer(t, sendFrames(match(10), match(11), match(13)))
+	defer server.Close()
+
+	events := make(chan gdax.LivenessEvent, 10)
+	stream, err := gdax.Stream(context.Background(), &feedSubscription, &gdax.StreamOptions{
+		EndPoint:   endPoint,
+		OnLiveness: func(event gdax.LivenessEvent) { events <- event },
+	})
+	assert.NoError(err)
+	defer stream.Close()
+
+	for i := 0; i < 3; i++ {
+		<-stream.Messages()
+	}
+	event := <-events
+	assert.Equal(event.Kind, gdax.TradeGap)
+	assert.Equal(event.Expected, int64(12))
+	assert.Equal(event.Actual, int64(13))
+	status, _ := stream.Status("BTC-USD")
+	assert.Equal(status.LastTradeID, int64(13))
+}
+
+func TestStreamReconnectWhenStale(t *testing.T) {
+	assert := assert.New(t)
+
+	server, endPoint := newFeedServer(t, sendFrames(matchJSON))
+	defer server.Close()
+
+	events := make(chan gdax.LivenessEvent, 10)
+	stream, err := gdax.Stream(context.Background(), &feedSubscription, &gdax.StreamOptions{
+		EndPoint:   endPoint,
+		StaleAfter: 50 * time.Millisecond,
+		Reconnect:  true,
+		OnLiveness: func(event gdax.LivenessEvent) { events <- event },
+	})
+	assert.NoError(err)
+	defer stream.Close()
+
+	assert.Eventually(func() bool { return stream.Reconnects() > 0 }, 5*time.Second, time.Millisecond)
+	var kinds []string
+	for len(kinds) < 2 {
+		kinds = append(kinds, (<-events).Kind)
+	}
+	assert.Equal(kinds, []string{gdax.Stale, gdax.Reconnected})
+	// the match is sent again after resubscribing.
+	assert.Equal((<-stream.Messages()).(gdax.Match).TradeID, int64(10))
+	assert.Equal((<-stream.Messages()).(gdax.Match).TradeID, int64(10))
+}
+
+func TestStreamPongTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	// the server never reads, so pings are never answered.
+	unblock := make(chan struct{})
+	server, endPoint := newFeedServer(t, func(conn *ws.Conn) error {
+		<-unblock
+		return nil
+	})
+	defer server.Close()
+	defer close(unblock)
+
+	stream, err := gdax.Stream(context.Background(), &feedSubscription, &gdax.StreamOptions{
+		EndPoint:     endPoint,
+		PingInterval: 10 * time.Millisecond,
+		PongTimeout:  50 * time.Millisecond,
+	})
+	assert.NoError(err)
+	defer stream.Close()
+
+	select {
+	case err := <-stream.Errors():
+		assert.Error(err)
+		assert.Contains(err.Error(), "timeout")
+	case <-time.After(5 * time.Second):
+		t.Fatal("unanswered pings did not time out")
+	}
+}
+
+// newShardedFeedServer creates a websocket server that sends three tickers for every subscribed product,
+// numbering the sequences of each product across every connection.
+// The first connection carrying dropProductID is dropped after its tickers are sent.
+func newShardedFeedServer(tb testing.TB, dropProductID string) (*httptest.Server, string) {
+	var (
+		upgrader  ws.Upgrader
+		mu        sync.Mutex
+		sequences = make(map[string]int)
+		dropped   bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			tb.Error(err)
+			return
+		}
+		defer conn.Close()
+		for {
+			var subscription gdax.Subscription
+			if err := conn.ReadJSON(&subscription); err != nil {
+				return
+			}
+			drop := false
+			for _, productID := range subscription.ProductIDs {
+				for i := 0; i < 3; i++ {
+					mu.Lock()
+					sequences[productID]++
+					ticker := strings.Replace(fmt.Sprintf(tickerJSON, productID), "3262786978", fmt.Sprint(sequences[productID]), 1)
+					mu.Unlock()
+					if err := conn.WriteMessage(ws.TextMessage, []byte(ticker)); err != nil {
+						return
+					}
+				}
+				mu.Lock()
+				if productID == dropProductID && !dropped {
+					dropped, drop = true, true
+				}
+				mu.Unlock()
+			}
+			if drop {
+				return
+			}
+		}
+	}))
+	return server, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestShardedStream(t *testing.T) {
+	assert := assert.New(t)
+
+	server, endPoint := newShardedFeedServer(t, "ETH-USD")
+	defer server.Close()
+
+	var rebalanced []string
+	var mu sync.Mutex
+	subscription := gdax.Subscription{
+		Type:       gdax.SubscribeType,
+		Channels:   []string{gdax.TickerType},
+		ProductIDs: []string{"BTC-USD", "ETH-USD", "LTC-USD"},
+	}
+	stream, err := gdax.ShardedStream(context.Background(), &subscription, 2, &gdax.StreamOptions{
+		EndPoint: endPoint,
+		OnLiveness: func(event gdax.LivenessEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			rebalanced = append(rebalanced, event.ProductID)
+		},
+	})
+	assert.NoError(err)
+	defer stream.Close()
+
+	sequences := make(map[string][]int64)
+	for i := 0; i < 12; i++ {
+		select {
+		case message := <-stream.Messages():
+			ticker := message.(gdax.Ticker)
+			sequences[ticker.ProductID] = append(sequences[ticker.ProductID], ticker.Sequence)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d messages were received", i)
+		}
+	}
+	assert.Equal(sequences["BTC-USD"], []int64{1, 2, 3})
+	assert.Equal(sequences["LTC-USD"], []int64{1, 2, 3})
+	assert.Equal(sequences["ETH-USD"], []int64{1, 2, 3, 4, 5, 6})
+	assert.Equal(stream.Shards(), 1)
+	assert.Equal(stream.Rebalances(), uint64(1))
+	mu.Lock()
+	assert.Equal(rebalanced, []string{"ETH-USD"})
+	mu.Unlock()
+	status, ok := stream.Status("ETH-USD")
+	assert.True(ok)
+	assert.Equal(status.Sequence, int64(6))
+}
+
+func TestShardedStreamAllDropped(t *testing.T) {
+	assert := assert.New(t)
+
+	server, endPoint := newFeedServer(t, func(conn *ws.Conn) error {
+		return conn.Close()
+	})
+	defer server.Close()
+
+	subscription := gdax.Subscription{
+		Type:       gdax.SubscribeType,
+		Channels:   []string{gdax.TickerType},
+		ProductIDs: []string{"BTC-USD", "ETH-USD"},
+	}
+	stream, err := gdax.ShardedStream(context.Background(), &subscription, 4, &gdax.StreamOptions{EndPoint: endPoint})
+	assert.NoError(err)
+	defer stream.Close()
+
+	select {
+	case err := <-stream.Errors():
+		assert.Error(err)
+		assert.NotEqual(err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not terminate")
+	}
+	_, ok := <-stream.Messages()
+	assert.False(ok)
+	assert.Equal(stream.Shards(), 0)
+}
+
+// failingSigner fails to sign once it has signed the specified number of times.
+type failingSigner struct {
+	mu        sync.Mutex
+	remaining int
+}
+
+// Sign signs with a fixed signature or fails.
+func (signer *failingSigner) Sign(string, string, string, string) (string, error) {
+	signer.mu.Lock()
+	defer signer.mu.Unlock()
+	if signer.remaining == 0 {
+		return "", errors.New("signer unavailable")
+	}
+	signer.remaining--
+	return "signature", nil
+}
+
+func TestShardedStreamRebalanceFailed(t *testing.T) {
+	assert := assert.New(t)
+
+	server, endPoint := newShardedFeedServer(t, "ETH-USD")
+	defer server.Close()
+
+	// the subscriptions of both connections are signed, but moving ETH-USD is not.
+	accessInfo, err := gdax.NewAccessInfo("key", "c2VjcmV0", "passphrase", gdax.WithSigner(&failingSigner{remaining: 2}))
+	assert.NoError(err)
+	var rebalanced []string
+	var mu sync.Mutex
+	subscription := gdax.Subscription{
+		Type:       gdax.SubscribeType,
+		Channels:   []string{gdax.TickerType},
+		ProductIDs: []string{"BTC-USD", "ETH-USD", "LTC-USD"},
+	}
+	stream, err := gdax.ShardedStream(context.Background(), &subscription, 2, &gdax.StreamOptions{
+		EndPoint:   endPoint,
+		AccessInfo: accessInfo,
+		OnLiveness: func(event gdax.LivenessEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			rebalanced = append(rebalanced, event.ProductID)
+		},
+	})
+	assert.NoError(err)
+	defer stream.Close()
+
+	for range stream.Messages() {
+	}
+	select {
+	case err := <-stream.Errors():
+		assert.ErrorContains(err, "could not move [ETH-USD]")
+		assert.ErrorContains(err, "signer unavailable")
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not terminate")
+	}
+	mu.Lock()
+	assert.Empty(rebalanced)
+	mu.Unlock()
+	_, ok := stream.Status("ETH-USD")
+	assert.False(ok)
+}
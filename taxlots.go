@@ -0,0 +1,388 @@
+package gdax
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Disposal Kinds
+const (
+	SaleDisposal     = "sale"
+	FeeDisposal      = "fee"
+	TransferDisposal = "transfer"
+)
+
+// TaxLotOptions configure the tax lots built from fills and ledger history.
+type TaxLotOptions struct {
+	// Method chooses the lots that a disposal closes.
+	Method LotMethod
+	// Currency is the currency that cost bases and proceeds are in. If empty, "USD" is used.
+	Currency string
+	// From and To limit the disposals to those in [From, To). Zero times do not limit them.
+	// All earlier history is still used to build the lots.
+	From time.Time
+	To   time.Time
+	// Rate gets the value of one unit of a currency in Currency at a time. It values the fills of products that are
+	// not quoted in Currency (e.g., ETH-BTC). It returns an error wrapping ErrNoPrice if the currency has no value at
+	// the time, and the fill is skipped; any other error stops BuildTaxLots. If nil, those fills are skipped.
+	Rate func(currency string, at time.Time) (float64, error)
+	// Skipped is called with each fill that is skipped because it could not be valued.
+	Skipped func(fill *Fill, err error)
+}
+
+// A TaxDisposal is the part of a lot of a currency that was disposed of.
+type TaxDisposal struct {
+	Currency string
+	// Kind is SaleDisposal for a sale or a trade for another currency, FeeDisposal for a fee paid in the currency, or
+	// TransferDisposal for a withdrawal, which carries its cost basis out without a gain.
+	Kind string
+	Size float64
+	// Acquired is when the lot was acquired, or the zero time if the size was not in any known lot.
+	Acquired time.Time
+	Disposed time.Time
+	// CostBasis and Proceeds are in the currency of the TaxLotOptions, after fees.
+	CostBasis float64
+	Proceeds  float64
+	// TradeID is the trade of a sale or a trade for another currency.
+	TradeID int64
+}
+
+// Gain gets the gain (or, if negative, the loss) of the disposal.
+func (disposal TaxDisposal) Gain() float64 {
+	return disposal.Proceeds - disposal.CostBasis
+}
+
+// A taxEvent is an acquisition or disposal of a currency, from a fill or a ledger entry.
+type taxEvent struct {
+	currency string
+	kind     string
+	at       time.Time
+	fill     Fill
+}
+
+// BuildTaxLots builds the disposals of every currency from fills and ledger history by currency (e.g., from
+// GetFills and GetAccountHistory), ordered by the time they were disposed of.
+//
+// Fills acquire and dispose of the base currency of their product. A fill of a product that is not quoted in the
+// currency of the options (e.g., ETH-BTC) also disposes of or acquires the quote currency: it is valued with the Rate
+// of the options at the time of the fill, and the fee is added to the cost basis of the base currency that is bought
+// or subtracted from the proceeds of the base currency that is sold. Fills that cannot be valued are skipped and
+// passed to Skipped; any other error of the Rate is returned. The fee of a fill is the sum of the FeeEntry and RebateEntry ledger entries of its trade in its
+// quote currency, or the fee of the fill if it has none. Other ledger entries are used as follows:
+//   - a TransferEntry in (a deposit) acquires a lot with a cost basis of zero, since its cost is not known.
+//   - a TransferEntry out (a withdrawal) is a TransferDisposal.
+//   - a FeeEntry is a FeeDisposal without proceeds.
+//
+// MatchEntry entries are ignored, since the fills describe the same trades.
+func BuildTaxLots(fills []*Fill, ledgers map[string][]*AccountHistory, options *TaxLotOptions) ([]TaxDisposal, error) {
+	var config TaxLotOptions
+	if options != nil {
+		config = *options
+	}
+	if config.Currency == "" {
+		config.Currency = "USD"
+	}
+
+	fees := make(map[string]float64)
+	var events []taxEvent
+	currencies := make([]string, 0, len(ledgers))
+	for currency := range ledgers {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		for _, entry := range ledgers[currency] {
+			_, quote, _ := strings.Cut(entry.Details.ProductID, "-")
+			if (entry.Type == FeeEntry || entry.Type == RebateEntry) && entry.Details.TradeID != "" && quote == currency {
+				fees[entry.Details.ProductID+"/"+entry.Details.TradeID] -= entry.Amount
+				continue
+			}
+			if currency == config.Currency {
+				continue
+			}
+			event := taxEvent{currency: currency, at: entry.CreatedAt, fill: Fill{Size: math.Abs(entry.Amount), Side: Sell}}
+			switch {
+			case entry.Type == TransferEntry && entry.Amount > 0:
+				event.fill.Side = Buy
+			case entry.Type == TransferEntry && entry.Amount < 0:
+				event.kind = TransferDisposal
+			case entry.Type == FeeEntry && entry.Amount < 0:
+				event.kind = FeeDisposal
+			default:
+				continue
+			}
+			events = append(events, event)
+		}
+	}
+
+	for _, fill := range fills {
+		fillEvents, err := config.fillEvents(fill, fees)
+		if errors.Is(err, ErrNoPrice) {
+			if config.Skipped != nil {
+				config.Skipped(fill, err)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, fillEvents...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].at.Equal(events[j].at) {
+			return events[i].at.Before(events[j].at)
+		}
+		return events[i].fill.TradeID < events[j].fill.TradeID
+	})
+
+	positions := make(map[string]*Position)
+	var disposals []TaxDisposal
+	for _, event := range events {
+		position, ok := positions[event.currency]
+		if !ok {
+			position = &Position{ProductID: event.currency + "-" + config.Currency, Method: config.Method}
+			positions[event.currency] = position
+		}
+		fill := event.fill
+		fill.CreatedAt = &event.at
+		if fill.Side == Buy {
+			position.add(&fill)
+			continue
+		}
+
+		// the size that is not in any known lot is disposed of without a cost basis, instead of opening a short lot.
+		known := math.Min(fill.Size, math.Max(position.Size(), 0))
+		unknown := fill.Size - known
+		if known > pnlEpsilon {
+			closed := len(position.Disposals)
+			part := fill
+			part.Size = known
+			part.Fee = fill.Fee * known / fill.Size
+			position.add(&part)
+			for _, disposal := range position.Disposals[closed:] {
+				disposals = append(disposals, event.disposal(disposal.Opened, disposal.Size, disposal.Cost, disposal.Proceeds))
+			}
+		}
+		if unknown > pnlEpsilon {
+			proceeds := fill.Price*unknown - fill.Fee*unknown/fill.Size
+			disposals = append(disposals, event.disposal(time.Time{}, unknown, 0, proceeds))
+		}
+	}
+
+	var inRange []TaxDisposal
+	for _, disposal := range disposals {
+		if !config.From.IsZero() && disposal.Disposed.Before(config.From) {
+			continue
+		}
+		if !config.To.IsZero() && !disposal.Disposed.Before(config.To) {
+			continue
+		}
+		inRange = append(inRange, disposal)
+	}
+	return inRange, nil
+}
+
+// fillEvents creates the events of a fill: an acquisition or disposal of its base currency and, if it is not quoted
+// in the currency of the options, a disposal or acquisition of its quote currency, all valued in that currency.
+func (config TaxLotOptions) fillEvents(fill *Fill, fees map[string]float64) ([]taxEvent, error) {
+	base, quote, ok := strings.Cut(fill.ProductID, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid product %q", fill.ProductID)
+	}
+	event := taxEvent{currency: base, kind: SaleDisposal, fill: *fill}
+	if fill.CreatedAt != nil {
+		event.at = *fill.CreatedAt
+	}
+	if fee, ok := fees[fill.ProductID+"/"+strconv.FormatInt(fill.TradeID, 10)]; ok {
+		event.fill.Fee = fee
+	}
+	if quote == config.Currency {
+		return []taxEvent{event}, nil
+	}
+
+	if config.Rate == nil {
+		return nil, fmt.Errorf("%w: no rate to value %s in %s", ErrNoPrice, quote, config.Currency)
+	}
+	rate, err := config.Rate(quote, event.at)
+	if err != nil {
+		return nil, err
+	}
+	if rate <= 0 {
+		return nil, fmt.Errorf("%w: %s in %s at %v", ErrNoPrice, quote, config.Currency, event.at)
+	}
+	// the quote currency is traded at its rate without a fee, since the fee is accounted for in the base currency.
+	exchanged := taxEvent{currency: quote, kind: SaleDisposal, at: event.at, fill: Fill{TradeID: fill.TradeID, Price: rate}}
+	value := fill.Price * fill.Size
+	if fill.Side == Buy {
+		exchanged.fill.Side, exchanged.fill.Size = Sell, value+event.fill.Fee
+	} else {
+		exchanged.fill.Side, exchanged.fill.Size = Buy, value-event.fill.Fee
+	}
+	event.fill.Price *= rate
+	event.fill.Fee *= rate
+	if exchanged.fill.Size <= pnlEpsilon {
+		return []taxEvent{event}, nil
+	}
+	return []taxEvent{event, exchanged}, nil
+}
+
+// disposal creates a TaxDisposal of the event. Fees and transfers have no gain.
+func (event taxEvent) disposal(acquired time.Time, size, cost, proceeds float64) TaxDisposal {
+	switch event.kind {
+	case FeeDisposal:
+		proceeds = 0
+	case TransferDisposal:
+		proceeds = cost
+	}
+	return TaxDisposal{
+		Currency:  event.currency,
+		Kind:      event.kind,
+		Size:      size,
+		Acquired:  acquired,
+		Disposed:  event.at,
+		CostBasis: cost,
+		Proceeds:  proceeds,
+		TradeID:   event.fill.TradeID,
+	}
+}
+
+// WriteTaxLots writes disposals as CSV with a header and a row per disposal, with the columns:
+//
+//	currency       the currency disposed of (e.g., BTC)
+//	kind           sale, fee, or transfer
+//	size           the amount of the currency
+//	acquired       when the lot was acquired (RFC 3339), or empty if unknown
+//	disposed       when the lot was disposed of (RFC 3339)
+//	holding days   the whole days between acquired and disposed, or empty if acquired is unknown
+//	cost basis     what was paid for the size, including fees
+//	proceeds       what the size was sold for, after fees
+//	gain           proceeds minus cost basis
+//	trade id       the trade of a sale, or empty
+//
+// Amounts are decimal numbers, with cost basis, proceeds, and gain in the currency of the TaxLotOptions.
+func WriteTaxLots(w io.Writer, disposals []TaxDisposal) error {
+	writer := csv.NewWriter(w)
+	header := []string{"currency", "kind", "size", "acquired", "disposed", "holding days", "cost basis", "proceeds", "gain", "trade id"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	formatFloat := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+	for _, disposal := range disposals {
+		var acquired, holdingDays, tradeID string
+		if !disposal.Acquired.IsZero() {
+			acquired = disposal.Acquired.UTC().Format(time.RFC3339)
+			holdingDays = strconv.Itoa(int(disposal.Disposed.Sub(disposal.Acquired) / (24 * time.Hour)))
+		}
+		if disposal.Kind == SaleDisposal {
+			tradeID = strconv.FormatInt(disposal.TradeID, 10)
+		}
+		row := []string{
+			disposal.Currency,
+			disposal.Kind,
+			formatFloat(disposal.Size),
+			acquired,
+			disposal.Disposed.UTC().Format(time.RFC3339),
+			holdingDays,
+			formatFloat(disposal.CostBasis),
+			formatFloat(disposal.Proceeds),
+			formatFloat(disposal.Gain()),
+			tradeID,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ExportTaxLots gets all fills and the ledger history of every account, builds tax lots from them with
+// BuildTaxLots, and writes the disposals in the date range of the options with WriteTaxLots.
+// If the options have no Rate, fills that are not quoted in their currency are valued at the close of the one minute
+// candle of the quote currency in that currency (e.g., BTC-USD) when they occurred. If the options have no Skipped,
+// nothing is written and an error is returned if any fill is skipped, so that the export is never silently incomplete.
+func (accessInfo *AccessInfo) ExportTaxLots(ctx context.Context, w io.Writer, options *TaxLotOptions) error {
+	var config TaxLotOptions
+	if options != nil {
+		config = *options
+	}
+	if config.Currency == "" {
+		config.Currency = "USD"
+	}
+	if config.Rate == nil {
+		config.Rate = accessInfo.candleRates(ctx, config.Currency)
+	}
+	var skipped []error
+	if config.Skipped == nil {
+		config.Skipped = func(fill *Fill, err error) {
+			skipped = append(skipped, fmt.Errorf("fill %d of %s: %w", fill.TradeID, fill.ProductID, err))
+		}
+	}
+
+	fills, err := accessInfo.GetFills().Collect(ctx)
+	if err != nil {
+		return err
+	}
+	accounts, err := accessInfo.GetAccounts().Collect(ctx)
+	if err != nil {
+		return err
+	}
+	ledgers := make(map[string][]*AccountHistory)
+	for _, account := range accounts {
+		history, err := accessInfo.GetAccountHistory(account.ID).Collect(ctx)
+		if err != nil {
+			return err
+		}
+		ledgers[account.Currency] = append(ledgers[account.Currency], history...)
+	}
+	disposals, err := BuildTaxLots(fills, ledgers, &config)
+	if err != nil {
+		return err
+	}
+	if len(skipped) > 0 {
+		return fmt.Errorf("%d fills could not be valued in %s: %w", len(skipped), config.Currency, errors.Join(skipped...))
+	}
+	return WriteTaxLots(w, disposals)
+}
+
+// candleRates creates a Rate that values a currency at the close of the one minute candle of its product in the quote
+// currency at a time. Each candle is only requested once, and requests are rate limited to the exchange's public rate
+// limit.
+func (accessInfo *AccessInfo) candleRates(ctx context.Context, quoteCurrency string) func(string, time.Time) (float64, error) {
+	limiter := rateLimiter{interval: time.Second / publicRequestsPerSecond}
+	// closes are the closes of the candles requested by product and minute, or zero if the minute had no trades.
+	closes := make(map[string]float64)
+	return func(currency string, at time.Time) (float64, error) {
+		productID := currency + "-" + quoteCurrency
+		start := at.Truncate(time.Minute)
+		key := productID + "/" + start.UTC().Format(time.RFC3339)
+		price, ok := closes[key]
+		if !ok {
+			if err := limiter.wait(ctx); err != nil {
+				return 0, err
+			}
+			candles, err := accessInfo.getCandles(ctx, productID, start, start.Add(time.Minute), time.Minute)
+			if err != nil {
+				return 0, err
+			}
+			for idx := len(candles) - 1; idx >= 0 && price == 0; idx-- {
+				if !candles[idx].Time.After(start) {
+					price = candles[idx].Close
+				}
+			}
+			closes[key] = price
+		}
+		if price == 0 {
+			return 0, fmt.Errorf("%w: no trades of %s at %v", ErrNoPrice, productID, at)
+		}
+		return price, nil
+	}
+}
@@ -0,0 +1,263 @@
+package gdax_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+const (
+	taxAccountsJSON = `
+		[
+		    {"id": "71452118-efc7-4cc4-8780-a5e22d4baa53", "currency": "BTC", "balance": "0.5", "available": "0.5", "holds": "0.0"},
+		    {"id": "e316cb9a-0808-4fd7-8914-97829c1925de", "currency": "USD", "balance": "100.0", "available": "100.0", "holds": "0.0"}
+		]
+	`
+	taxBTCLedgerJSON = `
+		[
+		    {"id": 2, "created_at": "2017-01-01T00:00:00Z", "amount": "1.0", "balance": "1.0", "type": "transfer", "details": {}}
+		]
+	`
+	taxUSDLedgerJSON = `
+		[
+		    {"id": 3, "created_at": "2018-03-01T00:00:00Z", "amount": "-5.0", "balance": "100.0", "type": "fee", "details": {"trade_id": "2", "product_id": "BTC-USD"}}
+		]
+	`
+	taxCryptoFillsJSON = `
+		[
+		    {"trade_id": 6, "product_id": "ETH-BTC", "price": "0.05", "size": "10", "created_at": "2018-03-01T00:00:40Z", "liquidity": "T", "fee": "0", "settled": true, "side": "buy"},
+		    {"trade_id": 5, "product_id": "ETH-BTC", "price": "0.05", "size": "10", "created_at": "2018-03-01T00:00:10Z", "liquidity": "T", "fee": "0", "settled": true, "side": "buy"}
+		]
+	`
+	taxFillsJSON = `
+		[
+		    {"trade_id": 2, "product_id": "BTC-USD", "price": "3000.00", "size": "0.50", "created_at": "2018-03-01T00:00:00Z", "liquidity": "T", "fee": "1.00", "settled": true, "side": "sell"}
+		]
+	`
+)
+
+// at creates a time at midnight UTC.
+func at(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// taxFill creates a fill of BTC-USD.
+func taxFill(tradeID int64, when time.Time, side string, price, size, fee float64) *gdax.Fill {
+	return &gdax.Fill{TradeID: tradeID, ProductID: "BTC-USD", CreatedAt: &when, Side: side, Price: price, Size: size, Fee: fee}
+}
+
+func TestBuildTaxLots(t *testing.T) {
+	assert := assert.New(t)
+
+	fills := []*gdax.Fill{
+		taxFill(3, at(2018, 6, 1), gdax.Sell, 4000, 0.5, 0),
+		taxFill(2, at(2018, 3, 1), gdax.Sell, 3000, 1.5, 1),
+		taxFill(1, at(2017, 6, 1), gdax.Buy, 1000, 1, 10),
+	}
+	ledgers := map[string][]*gdax.AccountHistory{
+		"BTC": {
+			{CreatedAt: at(2017, 1, 1), Amount: 1, Type: gdax.TransferEntry},
+			{CreatedAt: at(2018, 4, 1), Amount: -0.25, Type: gdax.TransferEntry},
+			{CreatedAt: at(2018, 5, 1), Amount: -0.01, Type: gdax.FeeEntry},
+			{CreatedAt: at(2018, 6, 1), Amount: -0.5, Type: gdax.MatchEntry},
+		},
+		"USD": {
+			{CreatedAt: at(2018, 3, 1), Amount: -5, Type: gdax.FeeEntry, Details: gdax.AccountHistoryDetails{TradeID: "2", ProductID: "BTC-USD"}},
+		},
+	}
+
+	disposals, err := gdax.BuildTaxLots(fills, ledgers, &gdax.TaxLotOptions{Method: gdax.FIFO})
+	assert.NoError(err)
+	assert.Len(disposals, 6)
+
+	// the deposit has no cost basis, and the ledger fee of the sale replaces the fee of the fill.
+	assert.Equal(disposals[0].Acquired, at(2017, 1, 1))
+	assert.Equal(disposals[0].CostBasis, 0.0)
+	assert.InDelta(disposals[0].Proceeds, 3000-5/1.5, 1e-9)
+	assert.Equal(disposals[1].Acquired, at(2017, 6, 1))
+	assert.Equal(disposals[1].Size, 0.5)
+	assert.InDelta(disposals[1].CostBasis, 505, 1e-9)
+
+	assert.Equal(disposals[2].Kind, gdax.TransferDisposal)
+	assert.InDelta(disposals[2].Gain(), 0, 1e-9)
+	assert.Equal(disposals[3].Kind, gdax.FeeDisposal)
+	assert.InDelta(disposals[3].CostBasis, 10.1, 1e-9)
+	assert.Equal(disposals[3].Proceeds, 0.0)
+
+	// only 0.24 BTC of the last sale was in a known lot.
+	assert.InDelta(disposals[4].Size, 0.24, 1e-9)
+	assert.InDelta(disposals[5].Size, 0.26, 1e-9)
+	assert.True(disposals[5].Acquired.IsZero())
+	assert.InDelta(disposals[5].Gain(), 1040, 1e-9)
+
+	disposals, err = gdax.BuildTaxLots(fills, ledgers, &gdax.TaxLotOptions{Method: gdax.LIFO, From: at(2018, 3, 15), To: at(2018, 5, 15)})
+	assert.NoError(err)
+	assert.Len(disposals, 2)
+	assert.InDelta(disposals[0].CostBasis, 0, 1e-9)
+
+	var buffer bytes.Buffer
+	assert.NoError(gdax.WriteTaxLots(&buffer, disposals))
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Equal(lines, []string{
+		"currency,kind,size,acquired,disposed,holding days,cost basis,proceeds,gain,trade id",
+		"BTC,transfer,0.25,2017-01-01T00:00:00Z,2018-04-01T00:00:00Z,455,0,0,0,",
+		"BTC,fee,0.01,2017-01-01T00:00:00Z,2018-05-01T00:00:00Z,485,0,0,0,",
+	})
+
+	// without a Rate, a fill that is not quoted in USD is skipped and reported.
+	var skipped []int64
+	disposals, err = gdax.BuildTaxLots([]*gdax.Fill{{TradeID: 1, ProductID: "ETH-BTC", Side: gdax.Sell, Size: 1}}, nil, &gdax.TaxLotOptions{
+		Skipped: func(fill *gdax.Fill, err error) {
+			assert.ErrorIs(err, gdax.ErrNoPrice)
+			skipped = append(skipped, fill.TradeID)
+		},
+	})
+	assert.NoError(err)
+	assert.Empty(disposals)
+	assert.Equal(skipped, []int64{1})
+
+	// any error of the Rate other than ErrNoPrice is returned instead of skipping the fill.
+	_, err = gdax.BuildTaxLots([]*gdax.Fill{{TradeID: 1, ProductID: "ETH-BTC", Side: gdax.Sell, Size: 1}}, nil, &gdax.TaxLotOptions{
+		Rate: func(string, time.Time) (float64, error) { return 0, errors.New("429 Too Many Requests") },
+	})
+	assert.EqualError(err, "429 Too Many Requests")
+}
+
+func TestBuildTaxLotsCryptoToCrypto(t *testing.T) {
+	assert := assert.New(t)
+
+	day2, day3 := at(2018, 1, 2), at(2018, 1, 3)
+	fills := []*gdax.Fill{
+		taxFill(1, at(2018, 1, 1), gdax.Buy, 10000, 1, 0),
+		{TradeID: 2, ProductID: "ETH-BTC", CreatedAt: &day2, Side: gdax.Buy, Price: 0.05, Size: 10},
+		{TradeID: 3, ProductID: "ETH-BTC", CreatedAt: &day3, Side: gdax.Sell, Price: 0.06, Size: 10, Fee: 0.006},
+	}
+	ledgers := map[string][]*gdax.AccountHistory{
+		"BTC": {
+			{CreatedAt: day2, Amount: -0.01, Type: gdax.FeeEntry, Details: gdax.AccountHistoryDetails{TradeID: "2", ProductID: "ETH-BTC"}},
+		},
+	}
+	rates := map[time.Time]float64{day2: 20000, day3: 25000}
+	options := &gdax.TaxLotOptions{
+		Rate: func(currency string, at time.Time) (float64, error) {
+			assert.Equal(currency, "BTC")
+			return rates[at], nil
+		},
+		Skipped: func(fill *gdax.Fill, err error) { t.Errorf("fill %d skipped: %v", fill.TradeID, err) },
+	}
+
+	disposals, err := gdax.BuildTaxLots(fills, ledgers, options)
+	assert.NoError(err)
+	assert.Len(disposals, 2)
+
+	// buying ETH disposes of the BTC it cost, including the fee from the ledger, at its value in USD.
+	assert.Equal(disposals[0].Currency, "BTC")
+	assert.Equal(disposals[0].Kind, gdax.SaleDisposal)
+	assert.InDelta(disposals[0].Size, 0.51, 1e-9)
+	assert.InDelta(disposals[0].CostBasis, 5100, 1e-9)
+	assert.InDelta(disposals[0].Proceeds, 10200, 1e-9)
+	assert.Equal(disposals[0].TradeID, int64(2))
+
+	// the ETH has the value of that BTC as its cost basis, and is sold for BTC worth 14850 USD after the fee.
+	assert.Equal(disposals[1].Currency, "ETH")
+	assert.Equal(disposals[1].Acquired, day2)
+	assert.InDelta(disposals[1].CostBasis, 10200, 1e-9)
+	assert.InDelta(disposals[1].Proceeds, 14850, 1e-9)
+
+	// the BTC bought with the ETH is a lot with that value as its cost basis.
+	fills = append(fills, taxFill(4, at(2018, 1, 4), gdax.Sell, 30000, 1.084, 0))
+	disposals, err = gdax.BuildTaxLots(fills, ledgers, options)
+	assert.NoError(err)
+	assert.Len(disposals, 4)
+	assert.Equal(disposals[3].Acquired, day3)
+	assert.InDelta(disposals[3].Size, 0.594, 1e-9)
+	assert.InDelta(disposals[3].CostBasis, 14850, 1e-9)
+}
+
+// mockTaxHistory mocks the accounts and ledgers of the tax lot tests.
+func mockTaxHistory() {
+	gock.New(gdax.EndPoint).
+		Get("/accounts").
+		Reply(http.StatusOK).
+		BodyString(taxAccountsJSON)
+	gock.New(gdax.EndPoint).
+		Get("/accounts/71452118-efc7-4cc4-8780-a5e22d4baa53/ledger").
+		Reply(http.StatusOK).
+		BodyString(taxBTCLedgerJSON)
+	gock.New(gdax.EndPoint).
+		Get("/accounts/e316cb9a-0808-4fd7-8914-97829c1925de/ledger").
+		Reply(http.StatusOK).
+		BodyString(taxUSDLedgerJSON)
+}
+
+func TestExportTaxLotsCryptoToCrypto(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	// both fills are in the same minute, so its BTC-USD candle is only requested once.
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		Reply(http.StatusOK).
+		BodyString(taxCryptoFillsJSON)
+	mockTaxHistory()
+	gock.New(gdax.EndPoint).
+		Get("/products/BTC-USD/candles").
+		MatchParam("start", "2018-03-01T00:00:00Z").
+		Reply(http.StatusOK).
+		BodyString(`[[1519862400, 9900, 10100, 9950, 10000, 12.3]]`)
+
+	var buffer bytes.Buffer
+	assert.NoError(accessInfo.ExportTaxLots(context.Background(), &buffer, nil))
+	assert.Equal(buffer.String(),
+		"currency,kind,size,acquired,disposed,holding days,cost basis,proceeds,gain,trade id\n"+
+			"BTC,sale,0.5,2017-01-01T00:00:00Z,2018-03-01T00:00:10Z,424,0,5000,5000,5\n"+
+			"BTC,sale,0.5,2017-01-01T00:00:00Z,2018-03-01T00:00:40Z,424,0,5000,5000,6\n")
+	assert.True(gock.IsDone())
+
+	// without any trades of BTC-USD in that minute, the fills cannot be valued, so nothing is written.
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		Reply(http.StatusOK).
+		BodyString(taxCryptoFillsJSON)
+	mockTaxHistory()
+	gock.New(gdax.EndPoint).
+		Get("/products/BTC-USD/candles").
+		Reply(http.StatusOK).
+		BodyString(`[]`)
+
+	buffer.Reset()
+	err = accessInfo.ExportTaxLots(context.Background(), &buffer, nil)
+	assert.ErrorIs(err, gdax.ErrNoPrice)
+	assert.Empty(buffer.String())
+}
+
+func TestExportTaxLots(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/fills").
+		Reply(http.StatusOK).
+		BodyString(taxFillsJSON)
+	mockTaxHistory()
+
+	var buffer bytes.Buffer
+	assert.NoError(accessInfo.ExportTaxLots(context.Background(), &buffer, &gdax.TaxLotOptions{Method: gdax.AverageCost}))
+	assert.Equal(buffer.String(),
+		"currency,kind,size,acquired,disposed,holding days,cost basis,proceeds,gain,trade id\n"+
+			"BTC,sale,0.5,2017-01-01T00:00:00Z,2018-03-01T00:00:00Z,424,0,1495,1495,2\n")
+}
@@ -0,0 +1,94 @@
+package gdax
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A Trade represents a single trade of a product.
+// Side is the side of the maker order, as in a Match.
+type Trade struct {
+	TradeID int64      `json:"trade_id"`
+	Time    *time.Time `json:"time,string"`
+	Price   float64    `json:"price,string"`
+	Size    float64    `json:"size,string"`
+	Side    string     `json:"side"`
+}
+
+// A TradeCollection is an iterator of Trades.
+type TradeCollection = Iterator[*Trade]
+
+// GetTrades gets the trades of a product, newest first.
+// Trade IDs can be used as cursors; e.g., paginating Older from a trade ID gets the trades before it.
+// Pages are fetched no faster than the exchange's public rate limit.
+func (accessInfo *AccessInfo) GetTrades(productID string) *TradeCollection {
+	// GET /products/<product-id>/trades
+	it := newIterator[*Trade](accessInfo, http.MethodGet, fmt.Sprintf("/products/%s/trades", productID), "", "", true)
+	fetch := it.fetch
+	limiter := rateLimiter{interval: time.Second / publicRequestsPerSecond}
+	it.fetch = func(ctx context.Context, cursor pagination) ([]*Trade, *pagination, error) {
+		if err := limiter.wait(ctx); err != nil {
+			return nil, nil, err
+		}
+		return fetch(ctx, cursor)
+	}
+	return it
+}
+
+// BackfillTrades gets the trades of a product after lastTradeID, oldest first, as Matches.
+// If untilTradeID is not zero, only the trades before it are returned; e.g., the trades missed by a TradeGap are
+// BackfillTrades(ctx, event.ProductID, event.Expected-1, event.Actual).
+// The trades are paged backward from the newest (or from untilTradeID) until lastTradeID is reached.
+// The returned Matches do not have a Sequence or order IDs, since the trades endpoint does not provide them.
+func (accessInfo *AccessInfo) BackfillTrades(ctx context.Context, productID string, lastTradeID, untilTradeID int64) ([]Match, error) {
+	trades := accessInfo.GetTrades(productID)
+	if untilTradeID != 0 {
+		if untilTradeID <= lastTradeID+1 {
+			return nil, nil
+		}
+		trades.Paginate(PageOptions{Cursor: strconv.FormatInt(untilTradeID, 10)})
+	}
+	var missed []Match
+	for {
+		trade, err := trades.Next(ctx)
+		if err == ErrDone {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if trade.TradeID <= lastTradeID {
+			break
+		}
+		if untilTradeID != 0 && trade.TradeID >= untilTradeID {
+			continue
+		}
+		missed = append(missed, trade.Match(productID))
+	}
+	for i, j := 0, len(missed)-1; i < j; i, j = i+1, j-1 {
+		missed[i], missed[j] = missed[j], missed[i]
+	}
+	return missed, nil
+}
+
+// Match converts a Trade of the specified product into a Match, so that it can be handled like a feed message.
+func (trade *Trade) Match(productID string) Match {
+	return Match{
+		message: message{Type: MatchType, ProductID: productID},
+		Time:    trade.Time,
+		TradeID: trade.TradeID,
+		Size:    trade.Size,
+		Price:   trade.Price,
+		Side:    trade.Side,
+	}
+}
+
+// timestamp gets the time of a Trade.
+func (trade *Trade) timestamp() time.Time {
+	if trade.Time == nil {
+		return time.Time{}
+	}
+	return *trade.Time
+}
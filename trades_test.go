@@ -0,0 +1,91 @@
+package gdax_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ljeabmreosn/gdax"
+	"github.com/stretchr/testify/assert"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+const (
+	tradesJSON = `
+		[
+		    {"time": "2014-11-07T22:19:28.578544Z", "trade_id": 74, "price": "10.00000000", "size": "0.01000000", "side": "buy"},
+		    {"time": "2014-11-07T01:08:43.642366Z", "trade_id": 73, "price": "100.00000000", "size": "0.01000000", "side": "sell"}
+		]
+	`
+	olderTradesJSON = `
+		[
+		    {"time": "2014-11-06T10:34:47.123456Z", "trade_id": 72, "price": "90.00000000", "size": "0.50000000", "side": "buy"},
+		    {"time": "2014-11-06T10:34:46.123456Z", "trade_id": 71, "price": "91.00000000", "size": "0.20000000", "side": "sell"}
+		]
+	`
+)
+
+func TestGetTrades(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/products/BTC-USD/trades").
+		Reply(http.StatusOK).
+		SetHeader("CB-BEFORE", "74").
+		SetHeader("CB-AFTER", "73").
+		BodyString(tradesJSON)
+	gock.New(gdax.EndPoint).
+		Get("/products/BTC-USD/trades").
+		MatchParam("after", "73").
+		Reply(http.StatusOK).
+		BodyString(`[]`)
+
+	trades, err := accessInfo.GetTrades("BTC-USD").Collect(context.Background())
+	assert.NoError(err)
+	assert.Len(trades, 2)
+	assert.Equal(trades[0].TradeID, int64(74))
+	assert.Equal(trades[0].Price, 10.0)
+	assert.Equal(trades[1].Side, gdax.Sell)
+}
+
+func TestBackfillTrades(t *testing.T) {
+	defer gock.Off()
+	assert := assert.New(t)
+
+	accessInfo, err := gdax.RetrieveAccessInfoFromEnvironmentVariables()
+	assert.NoError(err)
+
+	gock.New(gdax.EndPoint).
+		Get("/products/BTC-USD/trades").
+		MatchParam("after", "75").
+		Reply(http.StatusOK).
+		SetHeader("CB-BEFORE", "74").
+		SetHeader("CB-AFTER", "73").
+		BodyString(tradesJSON)
+	gock.New(gdax.EndPoint).
+		Get("/products/BTC-USD/trades").
+		MatchParam("after", "73").
+		Reply(http.StatusOK).
+		SetHeader("CB-BEFORE", "72").
+		SetHeader("CB-AFTER", "71").
+		BodyString(olderTradesJSON)
+
+	// the trades between 71 and 75 were missed.
+	matches, err := accessInfo.BackfillTrades(context.Background(), "BTC-USD", 71, 75)
+	assert.NoError(err)
+	assert.Len(matches, 3)
+	for idx, tradeID := range []int64{72, 73, 74} {
+		assert.Equal(matches[idx].TradeID, tradeID)
+		assert.Equal(matches[idx].ProductID, "BTC-USD")
+		assert.Equal(matches[idx].MessageType(), gdax.MatchType)
+	}
+	assert.True(gock.IsDone())
+
+	matches, err = accessInfo.BackfillTrades(context.Background(), "BTC-USD", 74, 75)
+	assert.NoError(err)
+	assert.Empty(matches)
+}
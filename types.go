@@ -1,18 +1,23 @@
 package gdax
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"reflect"
+	"slices"
 	"strings"
 	"time"
 )
 
-// A pageable is an interface for an iterator.
-type pageable interface {
-	HasNext() bool
-	Next() interface{}
-}
+// Directions
+const (
+	Older = "older"
+	Newer = "newer"
+)
+
+// ErrDone is returned by an Iterator's Next when there are no more elements.
+var ErrDone = errors.New("no more elements in iterator")
 
 // A pagination represents a cursor.
 type pagination struct {
@@ -21,17 +26,47 @@ type pagination struct {
 	limit  int
 }
 
-// A pageableCollection represents an iterator of HTTP responses.
-type pageableCollection struct {
-	currentPage        int
-	currentIndexInPage int
-	accessInfo         *AccessInfo
+// PageOptions control where an Iterator starts, which way it walks, and when it stops.
+type PageOptions struct {
+	// Cursor is the cursor to start from (e.g., a cursor returned by Before or After).
+	// If empty, the iterator starts at the newest page.
+	Cursor string
+	// Direction is either Older (the default) or Newer. Any other direction is an error.
+	// Walking Older yields the newest elements first. Walking Newer from a cursor only returns elements created after
+	// the element the cursor points to, oldest first.
+	Direction string
+	// Limit is the number of elements per page. If zero, the server's default is used.
+	Limit int
+	// StartTime and EndTime bound the elements returned by their creation time (i.e., [StartTime, EndTime)).
+	// The iterator stops paging once it walks past the bound in its direction; a zero time is unbounded.
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// A timestamped is an element of an Iterator with a creation time.
+type timestamped interface {
+	timestamp() time.Time
+}
+
+// A pageFetcher fetches a single page of elements at the specified cursor.
+// The returned cursor describes the neighbouring pages.
+type pageFetcher[T any] func(ctx context.Context, cursor pagination) ([]T, *pagination, error)
+
+// An Iterator is a lazily-evaluated iterator of elements of type T, fetched one page at a time.
+// Only the current page is held in memory.
+type Iterator[T any] struct {
+	fetch pageFetcher[T]
 	pagination
-	size                  int
-	finishedReadingPage   bool
-	pendingError          error
 	usesPaginationCursors bool
-	pages                 [][]interface{}
+	page                  []T
+	index                 int
+	exhausted             bool
+	direction             string
+	startTime             time.Time
+	endTime               time.Time
+	newestCursor          string
+	oldestCursor          string
+	err                   error
 }
 
 // A DayHourMin is a time struct with format mm,hh,dd.
@@ -44,21 +79,45 @@ func (d *DayHourMin) MarshalJSON() ([]byte, error) {
 	return []byte(d.Format("mm,hh,dd")), nil
 }
 
-// newPageableCollection creates a new pageable collection.
-// Some pageable collections do not have HTTP paginations cursors (i.e., the HTTP response returns a single JSON array).
+// newIterator creates a new Iterator over the JSON array(s) returned by the specified request.
+// Some iterators do not have HTTP paginations cursors (i.e., the HTTP response returns a single JSON array).
 // In this case, "usesPaginationCursors" should be false.
-func (accessInfo *AccessInfo) newPageableCollection(usesPaginationCursors bool) pageableCollection {
-	return pageableCollection{
-		currentPage:           -1,
-		accessInfo:            accessInfo,
+func newIterator[T any](accessInfo *AccessInfo, method, path, params, body string, usesPaginationCursors bool) *Iterator[T] {
+	fetch := func(ctx context.Context, cursor pagination) ([]T, *pagination, error) {
+		var page []T
+		respBody, next, err := accessInfo.collectionRequest(ctx, method, fmt.Sprintf("%s?%s&%s", path, params, cursor), body)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := json.Unmarshal(respBody, &page); err != nil {
+			return nil, nil, err
+		}
+		return page, next, nil
+	}
+	return newIteratorFromFetcher(fetch, usesPaginationCursors)
+}
+
+// newIteratorFromFetcher creates a new Iterator over the pages returned by the specified fetcher.
+func newIteratorFromFetcher[T any](fetch pageFetcher[T], usesPaginationCursors bool) *Iterator[T] {
+	return &Iterator[T]{
+		fetch:                 fetch,
 		pagination:            pagination{before: "", after: "", limit: -1},
-		size:                  0,
-		finishedReadingPage:   false,
-		pendingError:          nil,
 		usesPaginationCursors: usesPaginationCursors,
-		pages: nil,
+		direction:             Older,
 	}
 }
+
+// newSliceIterator creates an Iterator over a single page, which is produced when the first element is requested.
+func newSliceIterator[T any](produce func() ([]T, error)) *Iterator[T] {
+	return newIteratorFromFetcher(func(context.Context, pagination) ([]T, *pagination, error) {
+		page, err := produce()
+		if err != nil {
+			return nil, nil, err
+		}
+		return page, &pagination{limit: -1}, nil
+	}, false)
+}
+
 func (p pagination) String() string {
 	var (
 		before string
@@ -77,53 +136,169 @@ func (p pagination) String() string {
 	return strings.Join(stringFilter([]string{before, after, limit}, notEmpty), "&")
 }
 
-// hasNext determines if the pageableCollection has any more elements.
-// Also, this function loads the additional elements into memory if there are more elements.
-func (c *pageableCollection) hasNext(method, path, params, body string, container interface{}) bool {
-	if len(c.pages) == 0 || c.currentIndexInPage == len(c.pages[c.currentPage]) {
-		if len(c.pages) > 0 && !c.usesPaginationCursors {
-			return false
+// Paginate sets where the iterator starts, which way it walks, and when it stops.
+// This function must be called before the first call to Next, which returns an error if the direction is unknown.
+func (it *Iterator[T]) Paginate(options PageOptions) *Iterator[T] {
+	it.direction = Older
+	it.pagination = pagination{before: "", after: options.Cursor, limit: -1}
+	it.err = nil
+	switch options.Direction {
+	case "", Older:
+	case Newer:
+		it.direction = Newer
+		it.pagination.before, it.pagination.after = options.Cursor, ""
+	default:
+		it.err = fmt.Errorf("unknown direction %q (expected %q or %q)", options.Direction, Older, Newer)
+	}
+	if options.Limit > 0 {
+		it.pagination.limit = options.Limit
+	}
+	it.startTime = options.StartTime
+	it.endTime = options.EndTime
+	return it
+}
+
+// Before gets the cursor pointing at the newest element yielded so far, or at an older element of its page if the
+// page was cut by EndTime. Paginating Newer from this cursor resumes with elements created after it.
+func (it *Iterator[T]) Before() string {
+	return it.newestCursor
+}
+
+// After gets the cursor pointing at the oldest element yielded so far, or at a newer element of its page if the page
+// was cut by StartTime. Paginating Older from this cursor resumes with elements created before it.
+func (it *Iterator[T]) After() string {
+	return it.oldestCursor
+}
+
+// Next gets the next element from the iterator, fetching the next page if needed.
+// Next returns ErrDone when there are no more elements.
+// If Next returns any other error, calling Next again retries fetching the same page.
+func (it *Iterator[T]) Next(ctx context.Context) (T, error) {
+	var zero T
+	for it.index >= len(it.page) {
+		if it.exhausted {
+			return zero, ErrDone
+		}
+		if err := it.fetchPage(ctx); err != nil {
+			return zero, err
 		}
-		c.finishedReadingPage = true
-	} else if !c.finishedReadingPage {
-		return true
 	}
+	elem := it.page[it.index]
+	it.index++
+	return elem, nil
+}
 
-	respBody, cursor, err := c.accessInfo.collectionRequest(method, fmt.Sprintf("%s?%s&%s", path, params, c.pagination), body)
-	if err != nil {
-		c.pendingError = err
-		return true
+// Collect gets all remaining elements from the iterator.
+func (it *Iterator[T]) Collect(ctx context.Context) ([]T, error) {
+	var elems []T
+	for {
+		elem, err := it.Next(ctx)
+		if err == ErrDone {
+			return elems, nil
+		}
+		if err != nil {
+			return elems, err
+		}
+		elems = append(elems, elem)
 	}
-	err = json.Unmarshal([]byte(respBody), &container)
+}
+
+// fetchPage replaces the current page with the next page.
+func (it *Iterator[T]) fetchPage(ctx context.Context) error {
+	if it.err != nil {
+		return it.err
+	}
+	page, cursor, err := it.fetch(ctx, it.pagination)
 	if err != nil {
-		c.pendingError = err
-		return true
-	}
-	c.pagination = *cursor
-	page := reflect.ValueOf(container).Elem()
-	if page.Len() == 0 {
-		return false
-	}
-	z := make([]interface{}, page.Len())
-	for i := 0; i < page.Len(); i++ {
-		z[i] = page.Index(i)
-	}
-	var y [][]interface{}
-	a := reflect.ValueOf(reflect.ValueOf(c.pages).Interface())
-	c.pages = reflect.Append(a, reflect.ValueOf(z)).Convert(reflect.TypeOf(y)).Interface().([][]interface{})
-	c.size++
-	c.currentIndexInPage = 0
-	c.currentPage++
-	c.finishedReadingPage = false
-	return true
-}
-
-// next gets the next element from the pageableCollection.
-func (c *pageableCollection) next() (reflect.Value, error) {
-	if c.pendingError != nil {
-		return reflect.ValueOf(nil), c.pendingError
-	}
-	elem := c.pages[c.currentPage][c.currentIndexInPage]
-	c.currentIndexInPage++
-	return elem.(reflect.Value), nil
+		return err
+	}
+	if len(page) == 0 || !it.usesPaginationCursors {
+		it.exhausted = true
+	}
+	filtered, cutNewer, cutOlder := it.filterPage(page)
+	it.updateCursors(cursor, len(filtered) > 0, cutNewer, cutOlder)
+	if it.direction == Newer && it.usesPaginationCursors {
+		// pages are newest first, so the elements of a page are reversed to yield them in the order they were created.
+		slices.Reverse(filtered)
+	}
+	it.page = filtered
+	it.index = 0
+	return nil
+}
+
+// updateCursors moves the iterator's cursors to the page described by the specified response cursor.
+// The response cursor points at the newest (before) and oldest (after) elements of the page, which may have been cut
+// by the time bounds. The resume cursors never move past an element that was not yielded: if the newer end of the
+// page was cut, the newest cursor is the oldest element of the page, and if the older end was cut, the oldest cursor
+// is the newest element of the page. Resuming from them may return some elements of the page again, but never skips
+// any.
+func (it *Iterator[T]) updateCursors(cursor *pagination, yielded, cutNewer, cutOlder bool) {
+	newest, oldest := cursor.before, cursor.after
+	if cutNewer {
+		newest = ""
+		if yielded {
+			newest = cursor.after
+		}
+	}
+	if cutOlder {
+		oldest = ""
+		if yielded {
+			oldest = cursor.before
+		}
+	}
+
+	if it.direction == Newer {
+		if cursor.before != "" {
+			it.pagination.before = cursor.before
+		}
+		if newest != "" {
+			it.newestCursor = newest
+		}
+		if it.oldestCursor == "" {
+			it.oldestCursor = oldest
+		}
+		return
+	}
+	if cursor.after != "" {
+		it.pagination.after = cursor.after
+	} else {
+		it.exhausted = true
+	}
+	if oldest != "" {
+		it.oldestCursor = oldest
+	}
+	if it.newestCursor == "" {
+		it.newestCursor = newest
+	}
+}
+
+// filterPage removes the elements of a page that are not within the iterator's time bounds, and reports whether
+// elements were removed from the newer (i.e., at or after EndTime) or older (i.e., before StartTime) end of the page.
+// If an element is past the bound in the direction the iterator is walking, the iterator is exhausted.
+func (it *Iterator[T]) filterPage(page []T) (filtered []T, cutNewer, cutOlder bool) {
+	if it.startTime.IsZero() && it.endTime.IsZero() {
+		return page, false, false
+	}
+	filtered = page[:0]
+	for _, elem := range page {
+		if t, ok := any(elem).(timestamped); ok {
+			createdAt := t.timestamp()
+			if !it.startTime.IsZero() && createdAt.Before(it.startTime) {
+				if it.direction == Older {
+					it.exhausted = true
+				}
+				cutOlder = true
+				continue
+			}
+			if !it.endTime.IsZero() && !createdAt.Before(it.endTime) {
+				if it.direction == Newer {
+					it.exhausted = true
+				}
+				cutNewer = true
+				continue
+			}
+		}
+		filtered = append(filtered, elem)
+	}
+	return filtered, cutNewer, cutOlder
 }